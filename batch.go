@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type BatchCommand struct {
+	CommonCommand
+}
+
+func newBatchCommand(m *Main) *BatchCommand {
+	return &BatchCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *BatchCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	file := fs.String("f", "", "")
+	batchSize := fs.Int("batch-size", 0, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	// Parse the whole script before opening the database so that a syntax
+	// error never applies any of it. A file is checked in a first pass and
+	// streamed again to apply it; stdin can only be read once, so its
+	// operations are kept in memory.
+	var replay func(fn func(batchOp) error) error
+	if *file == "" {
+		var ops []batchOp
+		err := readBatch(cmd.Stdin, func(op batchOp) error {
+			ops = append(ops, op)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		replay = func(fn func(batchOp) error) error {
+			for _, op := range ops {
+				if err := fn(op); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	} else {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		if err := readBatch(f, func(batchOp) error { return nil }); err != nil {
+			return err
+		}
+		replay = func(fn func(batchOp) error) error {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			return readBatch(f, fn)
+		}
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	n, err := applyBatch(db, replay, *batchSize)
+	fmt.Fprintf(cmd.Stdout, "applied %d operations\n", n)
+	return err
+}
+
+// applyBatch applies every operation yielded by replay, committing after
+// each batchSize operations, or once at the end if batchSize is zero. It
+// returns the number of operations committed.
+func applyBatch(db *bolt.DB, replay func(fn func(batchOp) error) error, batchSize int) (int, error) {
+	var tx *bolt.Tx
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var applied, pending int
+	commit := func() error {
+		err := tx.Commit()
+		tx = nil
+		if err == nil {
+			applied, pending = applied+pending, 0
+		}
+		return err
+	}
+
+	err := replay(func(op batchOp) error {
+		if tx == nil {
+			var err error
+			if tx, err = db.Begin(true); err != nil {
+				return err
+			}
+		}
+		if err := op.apply(tx); err != nil {
+			return fmt.Errorf("line %d: %s", op.line, err)
+		}
+		if pending++; pending == batchSize {
+			return commit()
+		}
+		return nil
+	})
+	if err == nil && tx != nil {
+		err = commit()
+	}
+	return applied, err
+}
+
+// batchOp is a single parsed line of a batch script.
+type batchOp struct {
+	line int
+	name string
+	args []string
+}
+
+// apply executes the operation within tx.
+func (op batchOp) apply(tx *bolt.Tx) error {
+	if op.name == "mkbucket" {
+		_, err := boltview.CreateBucketIfNotExists(tx, op.args[0])
+		return err
+	}
+
+	bucket, err := boltview.LookupBucket(tx, op.args[0])
+	if err != nil {
+		return err
+	}
+	key := []byte(op.args[1])
+	if op.name == "put" {
+		err = bucket.Put(key, []byte(op.args[2]))
+	} else {
+		err = bucket.Delete(key)
+	}
+	if err != nil {
+		return err
+	}
+	return boltview.ClearExpiry(tx, op.args[0], key)
+}
+
+// batchArgN is the number of arguments each batch operation takes.
+var batchArgN = map[string]int{"put": 3, "del": 2, "mkbucket": 1}
+
+// readBatch parses a batch script and calls fn with each operation as it is
+// read. Blank lines and lines starting with "#" are ignored.
+func readBatch(r io.Reader, fn func(batchOp) error) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		op := batchOp{line: n}
+		op.name, line = splitField(line)
+		want, ok := batchArgN[op.name]
+		if !ok {
+			return fmt.Errorf("line %d: unknown operation %q", n, op.name)
+		}
+		if op.name == "put" {
+			// The value is the rest of the line so it may contain spaces.
+			bucketName, rest := splitField(line)
+			key, value := splitField(rest)
+			for _, arg := range []string{bucketName, key, value} {
+				if arg != "" {
+					op.args = append(op.args, arg)
+				}
+			}
+		} else {
+			op.args = strings.Fields(line)
+		}
+		if len(op.args) != want {
+			return fmt.Errorf("line %d: %s takes %d arguments", n, op.name, want)
+		}
+		if err := fn(op); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// splitField returns the first whitespace separated field of s and the
+// remainder with leading whitespace removed.
+func splitField(s string) (field, rest string) {
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i], strings.TrimLeftFunc(s[i:], unicode.IsSpace)
+	}
+	return s, ""
+}
+
+func (cmd *BatchCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt batch [-f FILE] [-batch-size N] PATH
+
+Batch reads a script from stdin and applies it in a single transaction:
+either every line takes effect or none does. The script is checked for
+syntax errors before the database is opened. Each line is one of:
+
+	put BUCKET_NAME KEY VALUE
+	del BUCKET_NAME KEY
+	mkbucket BUCKET_NAME
+
+VALUE is the rest of the line and may contain spaces. Blank lines and
+lines starting with "#" are ignored. The number of operations applied is
+printed when done.
+
+Additional options include:
+
+	-f FILE
+		Read the script from FILE instead of stdin, e.g. to replay a
+		saved set of changes. The file is read line by line, so it
+		isn't held in memory however large it is.
+	-batch-size N
+		Commit after every N operations instead of once at the end.
+		This keeps transactions small on large scripts, but if a
+		line fails, the batches before it stay applied.
+`, "\n")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// benchBucket is the temporary bucket filled by the bench command.
+const benchBucket = "bolttools-bench"
+
+type BenchCommand struct {
+	CommonCommand
+}
+
+func newBenchCommand(m *Main) *BenchCommand {
+	return &BenchCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *BenchCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	count := fs.Int("count", 10000, "")
+	keySize := fs.Int("keysize", 8, "")
+	valueSize := fs.Int("valuesize", 32, "")
+	batchSize := fs.Int("batch-size", 1000, "")
+	noSync := fs.Bool("no-sync", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if *count <= 0 || *keySize <= 0 || *valueSize < 0 || *batchSize <= 0 {
+		return errors.New("-count, -keysize and -batch-size must be positive")
+	}
+
+	// Generate the pairs up front so only bolt is timed. Keys are random,
+	// so a few may collide when -keysize is small.
+	pairs := make([][2][]byte, *count)
+	for i := range pairs {
+		pairs[i] = [2][]byte{make([]byte, *keySize), make([]byte, *valueSize)}
+		rand.Read(pairs[i][0])
+		rand.Read(pairs[i][1])
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	db.NoSync = *noSync
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(benchBucket))
+		if err == bolt.ErrBucketExists {
+			return fmt.Errorf("bucket %q already exists", benchBucket)
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket([]byte(benchBucket)) })
+	}()
+
+	// Write the pairs in transactions of -batch-size puts each.
+	start := time.Now()
+	for i := 0; i < len(pairs); i += *batchSize {
+		batch := pairs[i:]
+		if len(batch) > *batchSize {
+			batch = batch[:*batchSize]
+		}
+		if err := db.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(benchBucket))
+			for _, p := range batch {
+				if err := bucket.Put(p[0], p[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	cmd.printThroughput("Write", *count, *keySize+*valueSize, time.Since(start))
+
+	// Read every pair back in random order.
+	start = time.Now()
+	if err := db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(benchBucket))
+		for _, i := range rand.Perm(len(pairs)) {
+			if bucket.Get(pairs[i][0]) == nil {
+				return ErrKeyNotFound
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	cmd.printThroughput("Read", *count, *keySize+*valueSize, time.Since(start))
+	return nil
+}
+
+// printThroughput reports n operations of size bytes each taking d.
+func (cmd *BenchCommand) printThroughput(name string, n, size int, d time.Duration) {
+	sec := d.Seconds()
+	if sec <= 0 {
+		sec = time.Nanosecond.Seconds()
+	}
+	fmt.Fprintf(cmd.Stdout, "%-6s %d ops in %s (%.0f ops/sec, %s/sec)\n",
+		name+":", n, d, float64(n)/sec, humanizeBytes(int64(float64(n*size)/sec)))
+}
+
+func (cmd *BenchCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt bench [-count N] [-keysize K] [-valuesize V] [-batch-size N]
+                  [-no-sync] PATH
+
+Bench fills a temporary bucket with N random key-value pairs, reports the
+write throughput, then reads every pair back in random order and reports
+the read throughput. The bucket is deleted afterwards.
+
+Additional options include:
+
+	-count N
+		Write N pairs (default 10000).
+	-keysize K, -valuesize V
+		Size in bytes of each key (default 8) and value (default 32).
+	-batch-size N
+		Put N pairs per write transaction (default 1000).
+	-no-sync
+		Skip the fsync after each write transaction.
+`, "\n")
+}
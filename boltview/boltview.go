@@ -0,0 +1,758 @@
+// Package boltview implements the bucket and key-value operations used by
+// the bolttools command line so they can be embedded in other programs.
+package boltview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	ErrBucketNotFound = errors.New("bucket not found")
+	ErrKeyNotFound    = errors.New("key not found")
+	ErrKeyExists      = errors.New("key already exists")
+	ErrMaxDepth       = errors.New("buckets nested too deeply")
+	ErrNotABucket     = errors.New("not a bucket")
+	ErrNotAKey        = errors.New("is a nested bucket, not a key")
+)
+
+// BucketInfo describes a bucket. Nested buckets are named by their path
+// from the top level, e.g. "parent/child".
+type BucketInfo struct {
+	Name  string
+	KeyN  int
+	Depth int
+}
+
+// Pair is a single key-value pair read from a bucket.
+type Pair struct {
+	Key   []byte
+	Value []byte
+}
+
+// Buckets returns the top-level buckets in db in key order.
+func Buckets(db *bolt.DB) ([]BucketInfo, error) {
+	var infos []BucketInfo
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			infos = append(infos, BucketInfo{Name: string(name), KeyN: bucket.Stats().KeyN})
+			return nil
+		})
+	})
+	return infos, err
+}
+
+// AllBuckets is like Buckets but also returns every nested bucket, each
+// directly after its parent.
+func AllBuckets(db *bolt.DB) ([]BucketInfo, error) {
+	return AllBucketsDepth(db, 0)
+}
+
+// AllBucketsDepth is like AllBuckets but returns ErrMaxDepth instead of
+// descending into buckets nested more than maxDepth levels below the top
+// level. A maxDepth of zero or less means no limit.
+func AllBucketsDepth(db *bolt.DB, maxDepth int) ([]BucketInfo, error) {
+	var infos []BucketInfo
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return walkBuckets(&infos, string(name), 0, bucket, maxDepth)
+		})
+	})
+	return infos, err
+}
+
+// NestedBuckets returns the buckets nested directly in the bucket
+// bucketName, named by their path such as "parent/child". With recursive
+// set it also returns the buckets nested in those, as AllBucketsDepth
+// does, counting depth from the children of bucketName.
+func NestedBuckets(db *bolt.DB, bucketName string, recursive bool, maxDepth int) ([]BucketInfo, error) {
+	var infos []BucketInfo
+	err := db.View(func(tx *bolt.Tx) error {
+		parent, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		return parent.ForEach(func(k, v []byte) error {
+			if v != nil {
+				return nil
+			}
+			name, bucket := bucketName+"/"+string(k), parent.Bucket(k)
+			if !recursive {
+				infos = append(infos, BucketInfo{Name: name, KeyN: bucket.Stats().KeyN})
+				return nil
+			}
+			return walkBuckets(&infos, name, 0, bucket, maxDepth)
+		})
+	})
+	return infos, err
+}
+
+// walkBuckets appends bucket and every bucket nested in it to infos.
+func walkBuckets(infos *[]BucketInfo, name string, depth int, bucket *bolt.Bucket, maxDepth int) error {
+	if maxDepth > 0 && depth > maxDepth {
+		return fmt.Errorf("%w: %s", ErrMaxDepth, name)
+	}
+	*infos = append(*infos, BucketInfo{Name: name, KeyN: bucket.Stats().KeyN, Depth: depth})
+	return bucket.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return walkBuckets(infos, name+"/"+string(k), depth+1, bucket.Bucket(k), maxDepth)
+	})
+}
+
+// Size returns the total length of all keys and values in the bucket.
+// It requires a full scan of the bucket.
+func Size(db *bolt.DB, bucketName string) (int64, error) {
+	var n int64
+	err := ForEach(db, bucketName, func(k, v []byte) error {
+		n += int64(len(k) + len(v))
+		return nil
+	})
+	return n, err
+}
+
+// ForEach calls fn for every key-value pair in the bucket in key order.
+// Nested buckets are passed with a nil value. The slices are only valid
+// for the duration of the call.
+func ForEach(db *bolt.DB, bucketName string, fn func(k, v []byte) error) error {
+	return Scan(db, bucketName, ScanOptions{}, fn)
+}
+
+// ScanOptions restricts the pairs visited by Scan.
+type ScanOptions struct {
+	// After skips all keys up to and including this key.
+	After []byte
+
+	// Limit stops the scan after this many pairs. Zero means no limit.
+	Limit int
+
+	// Offset skips this many of the selected pairs before visiting any.
+	// Skipped pairs are still read, so After is cheaper for deep pages.
+	Offset int
+
+	// Prefix restricts the scan to keys starting with this prefix.
+	Prefix []byte
+
+	// From skips keys that sort before this key.
+	From []byte
+
+	// To stops the scan at the first key that sorts at or after this key,
+	// so From and To select a half-open range.
+	To []byte
+
+	// ExcludePrefix skips keys starting with this prefix.
+	ExcludePrefix []byte
+
+	// Reverse visits the pairs in descending key order, starting from the
+	// last one. After then skips keys down to and including After.
+	Reverse bool
+
+	// NoBuckets skips nested buckets so only keys with values are
+	// visited and counted towards Limit.
+	NoBuckets bool
+
+	// Filter, if set, skips the pairs for which it returns false. Skipped
+	// pairs don't count towards Limit.
+	Filter func(k, v []byte) bool
+}
+
+// Scan is like ForEach but only visits the pairs selected by opts. It
+// seeks the cursor instead of scanning from the first key.
+func Scan(db *bolt.DB, bucketName string, opts ScanOptions, fn func(k, v []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+
+		cursor := bucket.Cursor()
+		k, v := seekStart(cursor, opts)
+		next := cursor.Next
+		if opts.Reverse {
+			next = cursor.Prev
+		}
+
+		skip := opts.Offset
+		for n := 0; k != nil && bytes.HasPrefix(k, opts.Prefix); k, v = next() {
+			if opts.Limit > 0 && n >= opts.Limit {
+				break
+			} else if !opts.Reverse && opts.To != nil && bytes.Compare(k, opts.To) >= 0 {
+				break
+			} else if opts.Reverse && bytes.Compare(k, opts.From) < 0 {
+				break
+			} else if opts.NoBuckets && v == nil {
+				continue
+			} else if opts.ExcludePrefix != nil && bytes.HasPrefix(k, opts.ExcludePrefix) {
+				continue
+			} else if opts.Filter != nil && !opts.Filter(k, v) {
+				continue
+			} else if skip > 0 {
+				skip--
+				continue
+			}
+			if err := fn(k, v); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+// seekStart positions cursor at the first pair selected by opts in the
+// direction of the scan and returns it.
+func seekStart(cursor *bolt.Cursor, opts ScanOptions) ([]byte, []byte) {
+	if !opts.Reverse {
+		// Seek to whichever of After, Prefix and From comes latest.
+		start := opts.Prefix
+		if bytes.Compare(opts.From, start) > 0 {
+			start = opts.From
+		}
+		switch {
+		case opts.After != nil && bytes.Compare(opts.After, start) >= 0:
+			k, v := cursor.Seek(opts.After)
+			if bytes.Equal(k, opts.After) {
+				return cursor.Next()
+			}
+			return k, v
+		case start != nil:
+			return cursor.Seek(start)
+		}
+		return cursor.First()
+	}
+
+	// Walking backwards, start just before whichever of After, To and the
+	// end of the Prefix range comes first. All three bounds are exclusive.
+	var end []byte
+	for _, bound := range [][]byte{opts.After, opts.To, prefixEnd(opts.Prefix)} {
+		if bound != nil && (end == nil || bytes.Compare(bound, end) < 0) {
+			end = bound
+		}
+	}
+	if end == nil {
+		return cursor.Last()
+	} else if k, _ := cursor.Seek(end); k == nil {
+		return cursor.Last()
+	}
+	return cursor.Prev()
+}
+
+// prefixEnd returns the smallest key that sorts after every key starting
+// with prefix, or nil if there is none.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// List returns a copy of every key-value pair in the bucket in key order.
+// Use ForEach to stream large buckets instead.
+func List(db *bolt.DB, bucketName string) ([]Pair, error) {
+	var pairs []Pair
+	err := ForEach(db, bucketName, func(k, v []byte) error {
+		pairs = append(pairs, Pair{Key: clone(k), Value: clone(v)})
+		return nil
+	})
+	return pairs, err
+}
+
+// Get returns a copy of the value stored for key in the bucket.
+func Get(db *bolt.DB, bucketName string, key []byte) ([]byte, error) {
+	var value []byte
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		if v := bucket.Get(key); v != nil {
+			value = clone(v)
+			return nil
+		}
+		return ErrKeyNotFound
+	})
+	return value, err
+}
+
+// First returns a copy of the pair with the smallest key in the bucket,
+// or a zero Pair if the bucket is empty. The value of a nested bucket is
+// nil.
+func First(db *bolt.DB, bucketName string) (Pair, error) {
+	return boundary(db, bucketName, (*bolt.Cursor).First)
+}
+
+// Last is like First but returns the pair with the largest key.
+func Last(db *bolt.DB, bucketName string) (Pair, error) {
+	return boundary(db, bucketName, (*bolt.Cursor).Last)
+}
+
+// Tail returns copies of the last n pairs in the bucket in key order.
+func Tail(db *bolt.DB, bucketName string, n int) ([]Pair, error) {
+	var pairs []Pair
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil && len(pairs) < n; k, v = cursor.Prev() {
+			pairs = append(pairs, Pair{Key: clone(k), Value: clone(v)})
+		}
+		return nil
+	})
+
+	// The cursor walked backwards, so restore key order.
+	for i, j := 0, len(pairs)-1; i < j; i, j = i+1, j-1 {
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	return pairs, err
+}
+
+func boundary(db *bolt.DB, bucketName string, move func(*bolt.Cursor) ([]byte, []byte)) (Pair, error) {
+	var pair Pair
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		k, v := move(bucket.Cursor())
+		pair = Pair{Key: clone(k), Value: clone(v)}
+		return nil
+	})
+	return pair, err
+}
+
+// CreateBucket creates each of the buckets in a single transaction unless
+// it already exists. A name containing "/" is a path to a nested bucket and
+// any missing parents are created too, unless the name is an existing
+// top-level bucket as in LookupBucket.
+func CreateBucket(db *bolt.DB, bucketNames ...string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, bucketName := range bucketNames {
+			if _, err := CreateBucketIfNotExists(tx, bucketName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateBucketIfNotExists returns the bucket named by bucketName within tx,
+// creating it and any missing parents if needed. Names are resolved as in
+// LookupBucket, so an existing top-level bucket whose name contains "/" is
+// returned as it is.
+func CreateBucketIfNotExists(tx *bolt.Tx, bucketName string) (*bolt.Bucket, error) {
+	if bucket := tx.Bucket([]byte(bucketName)); bucket != nil {
+		return bucket, nil
+	}
+	segments := strings.Split(bucketName, "/")
+	bucket, err := tx.CreateBucketIfNotExists([]byte(segments[0]))
+	for i, segment := range segments[1:] {
+		if err != nil {
+			break
+		}
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(segment))
+		if err == bolt.ErrIncompatibleValue {
+			return nil, fmt.Errorf("%s: %w", strings.Join(segments[:i+2], "/"), ErrNotABucket)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", bucketName, err)
+	}
+	return bucket, nil
+}
+
+// DeleteBucket removes the bucket, which may be nested, with all of its
+// keys and nested buckets. It returns ErrBucketNotFound if it doesn't
+// exist.
+func DeleteBucket(db *bolt.DB, bucketName string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if _, err := LookupBucket(tx, bucketName); err != nil {
+			return err
+		}
+		parent, name, err := lookupParent(tx, bucketName)
+		if err != nil {
+			return err
+		} else if parent == nil {
+			return tx.DeleteBucket(name)
+		}
+		return parent.DeleteBucket(name)
+	})
+}
+
+// SetSequence sets the bucket's sequence counter, so the next call to
+// NextSequence (or InsertSeq) returns n+1.
+func SetSequence(db *bolt.DB, bucketName string, n uint64) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		return bucket.SetSequence(n)
+	})
+}
+
+// Insert stores value for key in the bucket. Unless overwrite is set it
+// returns ErrKeyExists if the key already has a value.
+func Insert(db *bolt.DB, bucketName string, key, value []byte, overwrite bool) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return InsertTx(tx, bucketName, key, value, overwrite)
+	})
+}
+
+// InsertTx is like Insert but writes within tx, so the insert commits or
+// rolls back together with the caller's other changes.
+func InsertTx(tx *bolt.Tx, bucketName string, key, value []byte, overwrite bool) error {
+	bucket, err := LookupBucket(tx, bucketName)
+	if err != nil {
+		return err
+	}
+	if bucket.Bucket(key) != nil {
+		return fmt.Errorf("%q %w", key, ErrNotAKey)
+	} else if !overwrite && bucket.Get(key) != nil {
+		return ErrKeyExists
+	}
+	if err := bucket.Put(key, value); err != nil {
+		return err
+	}
+	return ClearExpiry(tx, bucketName, key)
+}
+
+// ExpirySuffix is appended to a bucket name to name the companion bucket
+// that holds the expiry times written by InsertExpiring.
+const ExpirySuffix = "__exp"
+
+// InsertExpiring is like Insert but also records expires for key, as an
+// RFC3339 timestamp, in the bucket named bucketName plus ExpirySuffix,
+// creating it if needed. For a nested bucket the companion is its
+// sibling. Both writes happen in the same transaction.
+func InsertExpiring(db *bolt.DB, bucketName string, key, value []byte, overwrite bool, expires time.Time) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return InsertExpiringTx(tx, bucketName, key, value, overwrite, expires)
+	})
+}
+
+// InsertExpiringTx is like InsertExpiring but writes within tx.
+func InsertExpiringTx(tx *bolt.Tx, bucketName string, key, value []byte, overwrite bool, expires time.Time) error {
+	if err := InsertTx(tx, bucketName, key, value, overwrite); err != nil {
+		return err
+	}
+	exp, err := CreateBucketIfNotExists(tx, bucketName+ExpirySuffix)
+	if err != nil {
+		return err
+	}
+	return exp.Put(key, []byte(expires.UTC().Format(time.RFC3339)))
+}
+
+// ClearExpiry removes the expiry recorded for key by InsertExpiring, if
+// any. Every plain write or delete of a key calls it within the same
+// transaction, so a key written again without an expiry is no longer swept.
+func ClearExpiry(tx *bolt.Tx, bucketName string, key []byte) error {
+	exp, err := LookupBucket(tx, bucketName+ExpirySuffix)
+	if err != nil {
+		// Without a companion bucket there is nothing to clear.
+		return nil
+	}
+	return exp.Delete(key)
+}
+
+// SweepExpired deletes the keys of the bucket whose expiry recorded by
+// InsertExpiring is before now, together with their expiry entries, and
+// returns copies of the deleted keys. Expired entries for keys that no
+// longer exist are removed too. Entries that aren't valid timestamps and
+// keys that are nested buckets are left alone.
+func SweepExpired(db *bolt.DB, bucketName string, now time.Time) ([][]byte, error) {
+	var swept [][]byte
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		exp, err := LookupBucket(tx, bucketName+ExpirySuffix)
+		if err == ErrBucketNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Deleting under a moving cursor skips entries, so collect the
+		// expired keys first.
+		var expired [][]byte
+		if err := exp.ForEach(func(k, v []byte) error {
+			t, err := time.Parse(time.RFC3339, string(v))
+			if err == nil && t.Before(now) && bucket.Bucket(k) == nil {
+				expired = append(expired, clone(k))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if bucket.Get(k) != nil {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+				swept = append(swept, k)
+			}
+			if err := exp.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return swept, err
+}
+
+// PutMany stores all pairs in the bucket in a single transaction, creating
+// the bucket if it does not exist. Existing keys are overwritten.
+func PutMany(db *bolt.DB, bucketName string, pairs []Pair) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := CreateBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			if err := bucket.Put(p.Key, p.Value); err != nil {
+				return err
+			} else if err := ClearExpiry(tx, bucketName, p.Key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InsertSeq stores value in the bucket under the bucket's next sequence
+// number, encoded as 8 big-endian bytes, and returns that number.
+func InsertSeq(db *bolt.DB, bucketName string, value []byte) (uint64, error) {
+	var id uint64
+	err := db.Update(func(tx *bolt.Tx) error {
+		var err error
+		id, err = InsertSeqTx(tx, bucketName, value)
+		return err
+	})
+	return id, err
+}
+
+// InsertSeqTx is like InsertSeq but writes within tx.
+func InsertSeqTx(tx *bolt.Tx, bucketName string, value []byte) (uint64, error) {
+	bucket, err := LookupBucket(tx, bucketName)
+	if err != nil {
+		return 0, err
+	}
+	n, err := bucket.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n)
+	if err := bucket.Put(key, value); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Update replaces the value for an existing key in the bucket. It returns
+// ErrKeyNotFound instead of creating the key.
+func Update(db *bolt.DB, bucketName string, key, value []byte) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		if bucket.Get(key) == nil {
+			return ErrKeyNotFound
+		} else if err := bucket.Put(key, value); err != nil {
+			return err
+		}
+		return ClearExpiry(tx, bucketName, key)
+	})
+}
+
+// CompareAndSwap stores value for key in the bucket only if the current
+// value equals old, or if the key is absent when old is nil. It returns a
+// copy of the value found and whether value was stored.
+func CompareAndSwap(db *bolt.DB, bucketName string, key, old, value []byte) ([]byte, bool, error) {
+	var actual []byte
+	var swapped bool
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		v := bucket.Get(key)
+		actual = clone(v)
+		if (old == nil) != (v == nil) || !bytes.Equal(v, old) {
+			return nil
+		}
+		swapped = true
+		if err := bucket.Put(key, value); err != nil {
+			return err
+		}
+		return ClearExpiry(tx, bucketName, key)
+	})
+	return actual, swapped, err
+}
+
+// Delete removes key from the bucket. Deleting a missing key is not an
+// error.
+func Delete(db *bolt.DB, bucketName string, key []byte) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		} else if err := bucket.Delete(key); err != nil {
+			return err
+		}
+		return ClearExpiry(tx, bucketName, key)
+	})
+}
+
+// Move moves key from the src bucket to the dst bucket in a single
+// transaction, creating dst if needed. The key is stored as newKey in dst,
+// or under its original name if newKey is nil.
+func Move(db *bolt.DB, src string, key []byte, dst string, newKey []byte) error {
+	if newKey == nil {
+		newKey = key
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		from, err := LookupBucket(tx, src)
+		if err != nil {
+			return err
+		}
+		value := from.Get(key)
+		if value == nil {
+			return ErrKeyNotFound
+		}
+		if src == dst && bytes.Equal(key, newKey) {
+			return nil
+		}
+
+		to, err := CreateBucketIfNotExists(tx, dst)
+		if err != nil {
+			return err
+		}
+		if err := to.Put(newKey, clone(value)); err != nil {
+			return err
+		} else if err := from.Delete(key); err != nil {
+			return err
+		} else if err := ClearExpiry(tx, src, key); err != nil {
+			return err
+		}
+		return ClearExpiry(tx, dst, newKey)
+	})
+}
+
+// Truncate removes every key and nested bucket from the bucket but keeps
+// the bucket itself. The bucket is dropped and recreated, which resets its
+// sequence, unless keepSequence is set, in which case the keys are deleted
+// one by one instead.
+func Truncate(db *bolt.DB, bucketName string, keepSequence bool) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+
+		if !keepSequence {
+			parent, name, err := lookupParent(tx, bucketName)
+			if err != nil {
+				return err
+			} else if parent == nil {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+				_, err = tx.CreateBucket(name)
+				return err
+			}
+			if err := parent.DeleteBucket(name); err != nil {
+				return err
+			}
+			_, err = parent.CreateBucket(name)
+			return err
+		}
+
+		// Deleting under a moving cursor skips entries, so always restart
+		// from the first remaining key.
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.First() {
+			var err error
+			if v == nil {
+				err = bucket.DeleteBucket(k)
+			} else {
+				err = cursor.Delete()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LookupBucket returns the bucket named by bucketName within tx. A name
+// containing "/" that isn't itself a top-level bucket is a path to a
+// nested bucket, e.g. "parent/child". It returns ErrNotABucket naming the
+// path so far if a segment of the path is a key rather than a bucket, and
+// ErrBucketNotFound if the bucket doesn't exist.
+func LookupBucket(tx *bolt.Tx, bucketName string) (*bolt.Bucket, error) {
+	if bucket := tx.Bucket([]byte(bucketName)); bucket != nil {
+		return bucket, nil
+	}
+	segments := strings.Split(bucketName, "/")
+	bucket := tx.Bucket([]byte(segments[0]))
+	for i, segment := range segments[1:] {
+		if bucket == nil {
+			break
+		}
+		parent := bucket
+		if bucket = parent.Bucket([]byte(segment)); bucket == nil && parent.Get([]byte(segment)) != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(segments[:i+2], "/"), ErrNotABucket)
+		}
+	}
+	if bucket == nil {
+		return nil, ErrBucketNotFound
+	}
+	return bucket, nil
+}
+
+// lookupParent returns the bucket holding the existing bucket named by
+// bucketName, or nil if it is a top-level bucket, and its name within the
+// parent. The path is split as in LookupBucket.
+func lookupParent(tx *bolt.Tx, bucketName string) (*bolt.Bucket, []byte, error) {
+	segments := strings.Split(bucketName, "/")
+	if tx.Bucket([]byte(bucketName)) != nil || len(segments) == 1 {
+		return nil, []byte(bucketName), nil
+	}
+	parent := tx.Bucket([]byte(segments[0]))
+	for _, segment := range segments[1 : len(segments)-1] {
+		if parent == nil {
+			break
+		}
+		parent = parent.Bucket([]byte(segment))
+	}
+	if parent == nil {
+		return nil, nil, ErrBucketNotFound
+	}
+	return parent, []byte(segments[len(segments)-1]), nil
+}
+
+// clone returns a copy of b that outlives the transaction. A nil slice
+// stays nil so nested buckets remain distinguishable.
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,359 @@
+package boltview
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// openDB opens a new database in a temporary directory that is closed
+// when the test ends.
+func openDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// mustPut stores the "key", "value" pairs in the bucket, creating it.
+func mustPut(t *testing.T, db *bolt.DB, bucketName string, kv ...string) {
+	t.Helper()
+	var pairs []Pair
+	for i := 0; i < len(kv); i += 2 {
+		pairs = append(pairs, Pair{Key: []byte(kv[i]), Value: []byte(kv[i+1])})
+	}
+	if err := PutMany(db, bucketName, pairs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// keys returns the keys of the bucket as strings.
+func keys(t *testing.T, db *bolt.DB, bucketName string) []string {
+	t.Helper()
+	pairs, err := List(db, bucketName)
+	if err != nil {
+		t.Fatalf("%s: %s", bucketName, err)
+	}
+	var keys []string
+	for _, p := range pairs {
+		keys = append(keys, string(p.Key))
+	}
+	return keys
+}
+
+// bucketNames returns the names of every bucket in db.
+func bucketNames(t *testing.T, db *bolt.DB) []string {
+	t.Helper()
+	infos, err := AllBuckets(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// Writes to a nested path create and use the nested bucket rather than a
+// top-level bucket named by the whole path.
+func TestNestedPaths(t *testing.T) {
+	db := openDB(t)
+	if err := CreateBucket(db, "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	mustPut(t, db, "a/b", "k1", "v1", "k2", "v2")
+	if err := Move(db, "a/b", []byte("k1"), "a/d", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Truncate(db, "a/b", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := bucketNames(t, db), []string{"a", "a/b", "a/d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %q, want %q", got, want)
+	}
+	if got := keys(t, db, "a/b"); len(got) != 0 {
+		t.Fatalf("a/b keys = %q, want none", got)
+	}
+	if got, want := keys(t, db, "a/d"), []string{"k1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("a/d keys = %q, want %q", got, want)
+	}
+}
+
+// Only keys whose expiry has passed are swept, and a key written again
+// without an expiry is kept.
+func TestSweepExpired(t *testing.T) {
+	db := openDB(t)
+	now := time.Now()
+	mustPut(t, db, "b", "plain", "v")
+	for _, tt := range []struct {
+		key     string
+		expires time.Time
+	}{
+		{"old", now.Add(-time.Hour)},
+		{"new", now.Add(time.Hour)},
+		{"rewritten", now.Add(-time.Hour)},
+		{"deleted", now.Add(-time.Hour)},
+	} {
+		if err := InsertExpiring(db, "b", []byte(tt.key), []byte("v"), true, tt.expires); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Insert(db, "b", []byte("rewritten"), []byte("v2"), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(db, "b", []byte("deleted")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := keys(t, db, "b"+ExpirySuffix), []string{"new", "old"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expiry keys = %q, want %q", got, want)
+	}
+
+	swept, err := SweepExpired(db, "b", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(swept) != 1 || string(swept[0]) != "old" {
+		t.Fatalf("swept = %q, want [old]", swept)
+	}
+	if got, want := keys(t, db, "b"), []string{"new", "plain", "rewritten"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	db := openDB(t)
+	mustPut(t, db, "b", "a", "", "b1", "", "b2", "", "b3", "", "c", "", "d", "")
+	for _, tt := range []struct {
+		name string
+		opts ScanOptions
+		want []string
+	}{
+		{"all", ScanOptions{}, []string{"a", "b1", "b2", "b3", "c", "d"}},
+		{"after", ScanOptions{After: []byte("b1")}, []string{"b2", "b3", "c", "d"}},
+		{"after missing", ScanOptions{After: []byte("b")}, []string{"b1", "b2", "b3", "c", "d"}},
+		{"prefix", ScanOptions{Prefix: []byte("b")}, []string{"b1", "b2", "b3"}},
+		{"prefix after", ScanOptions{Prefix: []byte("b"), After: []byte("b1")}, []string{"b2", "b3"}},
+		{"prefix none", ScanOptions{Prefix: []byte("e")}, nil},
+		{"range", ScanOptions{From: []byte("b2"), To: []byte("d")}, []string{"b2", "b3", "c"}},
+		{"offset limit", ScanOptions{Offset: 2, Limit: 2}, []string{"b2", "b3"}},
+		{"exclude prefix", ScanOptions{ExcludePrefix: []byte("b")}, []string{"a", "c", "d"}},
+		{"reverse", ScanOptions{Reverse: true}, []string{"d", "c", "b3", "b2", "b1", "a"}},
+		{"reverse after", ScanOptions{Reverse: true, After: []byte("c")}, []string{"b3", "b2", "b1", "a"}},
+		{"reverse prefix", ScanOptions{Reverse: true, Prefix: []byte("b")}, []string{"b3", "b2", "b1"}},
+		{"reverse prefix none", ScanOptions{Reverse: true, Prefix: []byte("e")}, nil},
+		{"reverse range", ScanOptions{Reverse: true, From: []byte("b2"), To: []byte("d")}, []string{"c", "b3", "b2"}},
+		{"reverse limit", ScanOptions{Reverse: true, Limit: 2}, []string{"d", "c"}},
+	} {
+		var got []string
+		if err := Scan(db, "b", tt.opts, func(k, v []byte) error {
+			got = append(got, string(k))
+			return nil
+		}); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: keys = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSeekStart(t *testing.T) {
+	db := openDB(t)
+	mustPut(t, db, "b", "a", "", "b1", "", "b2", "", "c", "", "\xff", "")
+	for _, tt := range []struct {
+		name string
+		opts ScanOptions
+		want string
+	}{
+		{"first", ScanOptions{}, "a"},
+		{"from after prefix", ScanOptions{Prefix: []byte("b"), From: []byte("b2")}, "b2"},
+		{"after past from", ScanOptions{From: []byte("a"), After: []byte("b1")}, "b2"},
+		{"after before from", ScanOptions{From: []byte("b2"), After: []byte("a")}, "b2"},
+		{"past the end", ScanOptions{From: []byte("d")}, "\xff"},
+		{"last", ScanOptions{Reverse: true}, "\xff"},
+		{"reverse to", ScanOptions{Reverse: true, To: []byte("c")}, "b2"},
+		{"reverse prefix", ScanOptions{Reverse: true, Prefix: []byte("b")}, "b2"},
+		{"reverse prefix 0xff", ScanOptions{Reverse: true, Prefix: []byte("\xff")}, "\xff"},
+		{"reverse after and to", ScanOptions{Reverse: true, After: []byte("b2"), To: []byte("c")}, "b1"},
+	} {
+		if err := db.View(func(tx *bolt.Tx) error {
+			k, _ := seekStart(tx.Bucket([]byte("b")).Cursor(), tt.opts)
+			if string(k) != tt.want {
+				t.Errorf("%s: key = %q, want %q", tt.name, k, tt.want)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLookupBucket(t *testing.T) {
+	db := openDB(t)
+	mustPut(t, db, "a/b", "k", "nested")
+	mustPut(t, db, "x", "k", "v")
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("x/y"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("k"), []byte("top"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		want string
+		err  error
+	}{
+		{name: "a/b", want: "nested"},
+		{name: "x/y", want: "top"},
+		{name: "x", want: "v"},
+		{name: "a/c", err: ErrBucketNotFound},
+		{name: "c/b", err: ErrBucketNotFound},
+		{name: "x/k/z", err: ErrNotABucket},
+	} {
+		if err := db.View(func(tx *bolt.Tx) error {
+			b, err := LookupBucket(tx, tt.name)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			} else if err == nil && string(b.Get([]byte("k"))) != tt.want {
+				t.Errorf("%s: k = %q, want %q", tt.name, b.Get([]byte("k")), tt.want)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPutMany(t *testing.T) {
+	db := openDB(t)
+	mustPut(t, db, "a/b", "k1", "old", "k2", "v2")
+	mustPut(t, db, "a/b", "k1", "new", "k3", "v3")
+	pairs, err := List(db, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range pairs {
+		got = append(got, string(p.Key)+"="+string(p.Value))
+	}
+	if want := []string{"k1=new", "k2=v2", "k3=v3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pairs = %q, want %q", got, want)
+	}
+	if err := PutMany(openDB(t), "k/x", nil); err != nil {
+		t.Fatalf("empty batch: %s", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		src     string
+		key     string
+		dst     string
+		newKey  string
+		err     error
+		srcKeys []string
+		dstKeys []string
+	}{
+		{name: "other bucket", src: "a", key: "k1", dst: "b", srcKeys: []string{"k2"}, dstKeys: []string{"k1", "k3"}},
+		{name: "rename", src: "a", key: "k1", dst: "a", newKey: "k0", srcKeys: []string{"k0", "k2"}},
+		{name: "same key", src: "a", key: "k1", dst: "a", srcKeys: []string{"k1", "k2"}},
+		{name: "new nested bucket", src: "a", key: "k2", dst: "c/d", srcKeys: []string{"k1"}, dstKeys: []string{"k2"}},
+		{name: "missing key", src: "a", key: "k9", dst: "b", err: ErrKeyNotFound, srcKeys: []string{"k1", "k2"}, dstKeys: []string{"k3"}},
+		{name: "missing bucket", src: "z", key: "k1", dst: "b", err: ErrBucketNotFound, srcKeys: []string{"k1", "k2"}, dstKeys: []string{"k3"}},
+	} {
+		db := openDB(t)
+		mustPut(t, db, "a", "k1", "v1", "k2", "v2")
+		mustPut(t, db, "b", "k3", "v3")
+
+		var newKey []byte
+		if tt.newKey != "" {
+			newKey = []byte(tt.newKey)
+		}
+		if err := Move(db, tt.src, []byte(tt.key), tt.dst, newKey); err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		// srcKeys are the keys left in "a", even when moving from elsewhere.
+		if got := keys(t, db, "a"); !reflect.DeepEqual(got, tt.srcKeys) {
+			t.Errorf("%s: a keys = %q, want %q", tt.name, got, tt.srcKeys)
+		}
+		if got := keys(t, db, tt.dst); tt.dst != "a" && !reflect.DeepEqual(got, tt.dstKeys) {
+			t.Errorf("%s: %s keys = %q, want %q", tt.name, tt.dst, got, tt.dstKeys)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		bucket       string
+		keepSequence bool
+		sequence     uint64
+	}{
+		{bucket: "a", sequence: 0},
+		{bucket: "a", keepSequence: true, sequence: 5},
+		{bucket: "p/a", sequence: 0},
+		{bucket: "p/a", keepSequence: true, sequence: 5},
+	} {
+		db := openDB(t)
+		mustPut(t, db, tt.bucket, "k1", "v1", "k2", "v2")
+		mustPut(t, db, tt.bucket+"/child", "k", "v")
+		if err := SetSequence(db, tt.bucket, 5); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Truncate(db, tt.bucket, tt.keepSequence); err != nil {
+			t.Errorf("%s %v: %s", tt.bucket, tt.keepSequence, err)
+			continue
+		}
+		if got := keys(t, db, tt.bucket); len(got) != 0 {
+			t.Errorf("%s %v: keys = %q, want none", tt.bucket, tt.keepSequence, got)
+		}
+		if err := db.View(func(tx *bolt.Tx) error {
+			b, err := LookupBucket(tx, tt.bucket)
+			if err != nil {
+				return err
+			} else if b.Sequence() != tt.sequence {
+				t.Errorf("%s %v: sequence = %d, want %d", tt.bucket, tt.keepSequence, b.Sequence(), tt.sequence)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Truncate(openDB(t), "missing", false); err != ErrBucketNotFound {
+		t.Fatalf("missing bucket: err = %v, want %v", err, ErrBucketNotFound)
+	}
+}
+
+// Without overwrite an existing key is kept along with its expiry.
+func TestInsertExpiring_Exists(t *testing.T) {
+	db := openDB(t)
+	if err := CreateBucket(db, "b"); err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour)
+	if err := InsertExpiring(db, "b", []byte("k"), []byte("v1"), false, expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := InsertExpiring(db, "b", []byte("k"), []byte("v2"), false, expires.Add(time.Hour)); err != ErrKeyExists {
+		t.Fatalf("err = %v, want %v", err, ErrKeyExists)
+	}
+	if v, err := Get(db, "b", []byte("k")); err != nil || string(v) != "v1" {
+		t.Fatalf("k = %q, %v, want v1", v, err)
+	}
+	if swept, err := SweepExpired(db, "b", expires.Add(time.Minute)); err != nil || len(swept) != 1 {
+		t.Fatalf("swept = %q, %v, want [k]", swept, err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type CasCommand struct {
+	CommonCommand
+}
+
+func newCasCommand(m *Main) *CasCommand {
+	return &CasCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *CasCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 4
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+	if key == "" {
+		return ErrKeyRequired
+	}
+	// EXPECTED may be empty, so only require the arguments to be present.
+	if cmd.narg(fs) < 4 {
+		return ErrValueRequired
+	}
+	var expected []byte
+	if s := cmd.arg(fs, 2); s != "" {
+		expected = []byte(s)
+	}
+	value := []byte(cmd.arg(fs, 3))
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	actual, swapped, err := boltview.CompareAndSwap(db, bucketName, []byte(key), expected, value)
+	if err != nil {
+		return err
+	} else if !swapped {
+		if actual == nil {
+			fmt.Fprintln(cmd.Stderr, "<null>")
+		} else {
+			fmt.Fprintf(cmd.Stderr, "%s\n", actual)
+		}
+		return ErrCondition
+	}
+	return nil
+}
+
+func (cmd *CasCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt cas PATH BUCKET_NAME KEY EXPECTED NEW
+
+Cas (compare-and-swap) sets KEY to NEW only if its current value is
+exactly EXPECTED, checking and writing in a single transaction. An empty
+EXPECTED ('') means the key must not exist yet.
+
+If the value doesn't match, nothing is written, the actual value (or
+<null> for a missing key) is printed to stderr and the command exits
+with status 4.
+`, "\n")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type CheckLockCommand struct {
+	CommonCommand
+}
+
+func newCheckLockCommand(m *Main) *CheckLockCommand {
+	return &CheckLockCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *CheckLockCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	// Unlike the other commands, don't wait indefinitely by default.
+	if !isSet(fs, "timeout") {
+		cmd.timeout = 100 * time.Millisecond
+	}
+
+	// A writer holds an exclusive lock on the file for as long as it has
+	// the database open, so a read-only open times out while it runs.
+	db, err := cmd.openDB(cmd.path(fs), true)
+	if errors.Is(err, ErrLocked) {
+		return ErrLocked
+	} else if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func (cmd *CheckLockCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt check-lock [-timeout 100ms] PATH
+
+Check-lock prints nothing and exits with status 0 if the database can be
+opened for reading, or with status 5 if another process has it open for
+writing. Use it to decide whether it is safe to copy the file:
+
+	if bolt check-lock my.db; then cp my.db backup.db; fi
+
+Additional options include:
+
+	-timeout DURATION
+		How long to wait for the lock before reporting it as held
+		(default 100ms).
+`, "\n")
+}
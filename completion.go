@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// commandNames lists the commands offered by shell completion.
+var commandNames = []string{
+	"help", "buckets", "list", "get", "first", "last", "tail", "exists",
+	"find", "insert", "update", "set-many", "delete", "cas", "replace",
+	"move", "truncate", "batch", "import-csv", "load", "expire-sweep",
+	"watch", "create-bucket", "delete-bucket", "set-sequence", "dump",
+	"export", "import", "diff", "schema", "summary", "checksum",
+	"salvage", "check-lock", "shell", "bench", "completion",
+}
+
+type CompletionCommand struct {
+	CommonCommand
+}
+
+func newCompletionCommand(m *Main) *CompletionCommand {
+	return &CompletionCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *CompletionCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	var script string
+	switch shell := fs.Arg(0); shell {
+	case "":
+		return ErrShellRequired
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	default:
+		return fmt.Errorf("unknown shell %q: must be bash or zsh", shell)
+	}
+	fmt.Fprint(cmd.Stdout, strings.Replace(script, "@COMMANDS@", strings.Join(commandNames, " "), -1))
+	return nil
+}
+
+// bashCompletion completes command names, the database path and, for the
+// argument after the path, bucket names read with "buckets -names-only".
+const bashCompletion = `_bolt() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=($(compgen -W "@COMMANDS@" -- "$cur"))
+		return
+	fi
+
+	# Count the positional arguments before the cursor; the first is PATH.
+	local i path= n=0
+	for ((i = 2; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		-*) continue ;;
+		esac
+		n=$((n + 1))
+		[ "$n" -eq 1 ] && path=${COMP_WORDS[i]}
+	done
+
+	if [ "$n" -eq 0 ]; then
+		COMPREPLY=($(compgen -f -- "$cur"))
+	elif [ "$n" -eq 1 ] && [ -f "$path" ]; then
+		local buckets
+		buckets=$("${COMP_WORDS[0]}" buckets -names-only "$path" 2>/dev/null)
+		COMPREPLY=($(compgen -W "$buckets" -- "$cur"))
+	fi
+}
+complete -F _bolt bolt bolttools
+`
+
+// zshCompletion is the zsh equivalent of bashCompletion.
+const zshCompletion = `_bolt() {
+	local -a commands args buckets
+	commands=(@COMMANDS@)
+	if (( CURRENT == 2 )); then
+		compadd -a commands
+		return
+	fi
+
+	# The positional arguments before the cursor; the first is PATH.
+	args=(${words[3,CURRENT-1]:#-*})
+	if (( ${#args} == 0 )); then
+		_files
+	elif (( ${#args} == 1 )) && [[ -f ${args[1]} ]]; then
+		buckets=(${(f)"$(${words[1]} buckets -names-only ${args[1]} 2>/dev/null)"})
+		compadd -a buckets
+	fi
+}
+compdef _bolt bolt bolttools
+`
+
+func (cmd *CompletionCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt completion bash|zsh
+
+Completion prints a shell completion script for bolt. It completes command
+names, the database path and bucket names, which are read from the
+database on the command line. Load it with:
+
+	source <(bolt completion bash)
+
+zsh users should run compinit before sourcing the zsh script.
+`, "\n")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type CreateBucketCommand struct {
+	CommonCommand
+}
+
+func newCreateBucketCommand(m *Main) *CreateBucketCommand {
+	return &CreateBucketCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *CreateBucketCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = anyArgs
+
+	var bucketNames []string
+	for i := 0; i < cmd.narg(fs); i++ {
+		bucketNames = append(bucketNames, cmd.arg(fs, i))
+	}
+	if len(bucketNames) == 0 || bucketNames[0] == "" {
+		return ErrBucketRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	return boltview.CreateBucket(db, bucketNames...)
+}
+
+func (cmd *CreateBucketCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt create-bucket PATH BUCKET_NAME [BUCKET_NAME...]
+
+Create-bucket creates each bucket that does not already exist, all in one
+transaction. A nested path such as users/settings creates any missing
+parent buckets as well. Use "bolt delete-bucket" to remove a bucket.
+`, "\n")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type DeleteBucketCommand struct {
+	CommonCommand
+}
+
+func newDeleteBucketCommand(m *Main) *DeleteBucketCommand {
+	return &DeleteBucketCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *DeleteBucketCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	yes := fs.Bool("yes", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if !*yes {
+		if err := cmd.confirm(db, bucketName); err != nil {
+			return err
+		}
+	}
+	return boltview.DeleteBucket(db, bucketName)
+}
+
+// confirm asks on stderr whether to delete the bucket, saying how much it
+// holds, and reads the answer from stdin. Without a terminal to ask on it
+// fails and points at -yes.
+func (cmd *DeleteBucketCommand) confirm(db *bolt.DB, bucketName string) error {
+	var keys, buckets int
+	if err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := boltview.LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		stats := bucket.Stats()
+		keys, buckets = stats.KeyN-(stats.BucketN-1), stats.BucketN-1
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if f, ok := cmd.Stdin.(*os.File); !ok || !isTerminal(f) {
+		return fmt.Errorf("%w: pass -yes to delete bucket %s without asking", ErrNotConfirmed, bucketName)
+	}
+	fmt.Fprintf(cmd.Stderr, "Delete bucket %s with %d keys and %d nested buckets? [y/N] ", bucketName, keys, buckets)
+	answer, _ := bufio.NewReader(cmd.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return ErrNotConfirmed
+	}
+	return nil
+}
+
+func (cmd *DeleteBucketCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt delete-bucket [-yes] PATH BUCKET_NAME
+
+Delete-bucket removes the bucket with all of its keys and nested buckets.
+BUCKET_NAME may be a nested path such as users/settings, in which case only
+the last bucket is removed. To empty a bucket but keep it, use "bolt
+truncate" instead.
+
+Unless -yes is given, it says how many keys and nested buckets the bucket
+holds and asks for confirmation on the terminal. When stdin isn't a
+terminal, as in scripts, it fails without deleting anything.
+
+Additional options include:
+
+	-yes
+		Delete without asking.
+`, "\n")
+}
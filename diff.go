@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type DiffCommand struct {
+	CommonCommand
+}
+
+func newDiffCommand(m *Main) *DiffCommand {
+	return &DiffCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *DiffCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	help := fs.Bool("h", false, "")
+	bucketName := fs.String("bucket", "", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	// Open both databases.
+	dbA, err := cmd.openDB(cmd.path(fs), !cmd.rw)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dbA.Close() }()
+
+	dbB, err := cmd.openDB(cmd.arg(fs, 0), !cmd.rw)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dbB.Close() }()
+
+	return dbA.View(func(txA *bolt.Tx) error {
+		return dbB.View(func(txB *bolt.Tx) error {
+			if *bucketName != "" {
+				// A bucket missing on one side is diffed against nothing.
+				a, errA := boltview.LookupBucket(txA, *bucketName)
+				b, errB := boltview.LookupBucket(txB, *bucketName)
+				if errA != nil && errA != ErrBucketNotFound {
+					return errA
+				} else if errB != nil && errB != ErrBucketNotFound {
+					return errB
+				} else if a == nil && b == nil {
+					return ErrBucketNotFound
+				}
+				cmd.diffBucket([]byte(*bucketName), a, b)
+				return nil
+			}
+
+			// Walk the top-level bucket names of both files together.
+			diffCursors(txA.Cursor(), txB.Cursor(), func(name, _, _ []byte, inA, inB bool) {
+				var a, b *bolt.Bucket
+				if inA {
+					a = txA.Bucket(name)
+				}
+				if inB {
+					b = txB.Bucket(name)
+				}
+				cmd.diffBucket(name, a, b)
+			})
+			return nil
+		})
+	})
+}
+
+// diffBucket prints the differences between two versions of a bucket,
+// including its nested buckets. Either bucket may be nil if it only exists
+// on one side.
+func (cmd *DiffCommand) diffBucket(name []byte, a, b *bolt.Bucket) {
+	switch {
+	case a == nil:
+		fmt.Fprintf(cmd.Stdout, "+ %s\n", name)
+		_ = b.ForEach(func(k, v []byte) error {
+			cmd.diffKey(name, k, nil, v, false, true, nil, b)
+			return nil
+		})
+	case b == nil:
+		fmt.Fprintf(cmd.Stdout, "- %s\n", name)
+		_ = a.ForEach(func(k, v []byte) error {
+			cmd.diffKey(name, k, v, nil, true, false, a, nil)
+			return nil
+		})
+	default:
+		diffCursors(a.Cursor(), b.Cursor(), func(k, va, vb []byte, inA, inB bool) {
+			cmd.diffKey(name, k, va, vb, inA, inB, a, b)
+		})
+	}
+}
+
+// diffKey prints the difference for key k of the bucket name, where inA and
+// inB report which side has it. A nil value is a nested bucket, which is
+// compared under its path.
+func (cmd *DiffCommand) diffKey(name, k, va, vb []byte, inA, inB bool, a, b *bolt.Bucket) {
+	child := append(append(append([]byte{}, name...), '/'), k...)
+	switch {
+	case inA && inB && va == nil && vb == nil:
+		cmd.diffBucket(child, a.Bucket(k), b.Bucket(k))
+	case inA && inB && va != nil && vb != nil:
+		if !bytes.Equal(va, vb) {
+			fmt.Fprintf(cmd.Stdout, "~ %s\t%s\t%s\n", name, k, vb)
+		}
+	default:
+		// The key is on one side only, or is a bucket on one side and a
+		// value on the other.
+		if inA && va == nil {
+			cmd.diffBucket(child, a.Bucket(k), nil)
+		} else if inA {
+			fmt.Fprintf(cmd.Stdout, "- %s\t%s\n", name, k)
+		}
+		if inB && vb == nil {
+			cmd.diffBucket(child, nil, b.Bucket(k))
+		} else if inB {
+			fmt.Fprintf(cmd.Stdout, "+ %s\t%s\t%s\n", name, k, vb)
+		}
+	}
+}
+
+// diffCursors merges two cursors in key order and calls fn once per
+// distinct key with the value from each side. inA and inB report which
+// sides contain the key. Only one entry per cursor is held at a time.
+func diffCursors(a, b *bolt.Cursor, fn func(k, va, vb []byte, inA, inB bool)) {
+	ka, va := a.First()
+	kb, vb := b.First()
+	for ka != nil || kb != nil {
+		switch cmp := compareKeys(ka, kb); {
+		case cmp < 0:
+			fn(ka, va, nil, true, false)
+			ka, va = a.Next()
+		case cmp > 0:
+			fn(kb, nil, vb, false, true)
+			kb, vb = b.Next()
+		default:
+			fn(ka, va, vb, true, true)
+			ka, va = a.Next()
+			kb, vb = b.Next()
+		}
+	}
+}
+
+// compareKeys orders cursor keys, treating a nil key (an exhausted
+// cursor) as greater than any other key.
+func compareKeys(a, b []byte) int {
+	switch {
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return bytes.Compare(a, b)
+}
+
+func (cmd *DiffCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt diff [-bucket BUCKET_NAME] [-rw] PATH_A PATH_B
+
+Diff compares two databases and prints one line per difference:
+
+	+ BUCKET KEY VALUE	key (or bucket) only in PATH_B
+	- BUCKET KEY		key (or bucket) only in PATH_A
+	~ BUCKET KEY VALUE	value changed, VALUE is the one in PATH_B
+
+Fields are separated by tabs. Nested buckets are compared too, with BUCKET
+being their path such as parent/child, and a bucket only on one side is
+printed as "+ BUCKET" or "- BUCKET" followed by its contents. Both files
+are read in sorted key order so the comparison uses constant memory.
+
+Additional options include:
+
+	-bucket BUCKET_NAME
+		Only compare the given bucket.
+`, "\n")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+type DumpCommand struct {
+	CommonCommand
+}
+
+func newDumpCommand(m *Main) *DumpCommand {
+	return &DumpCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *DumpCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	help := fs.Bool("h", false, "")
+	progress := fs.Bool("progress", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	// Open database.
+	path := cmd.path(fs)
+	db, err := cmd.openDB(path, !cmd.rw)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	// Write script header. The restore target is the script's first argument.
+	fmt.Fprintln(cmd.Stdout, "#!/bin/sh")
+	fmt.Fprintf(cmd.Stdout, "# bolt dump of %s\n", path)
+	fmt.Fprintln(cmd.Stdout, "set -e")
+	fmt.Fprintln(cmd.Stdout, `BOLT=${BOLT:-bolttools}`)
+	fmt.Fprintln(cmd.Stdout, `DB=${1:?usage: $0 PATH}`)
+	fmt.Fprintln(cmd.Stdout, `touch "$DB"`)
+
+	var n int64
+	if *progress {
+		defer cmd.startProgress(&n)()
+	}
+
+	start := time.Now()
+	defer func() { cmd.verbosef("scan: %s, %d rows\n", time.Since(start), atomic.LoadInt64(&n)) }()
+
+	return db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return cmd.dumpBucket(string(name), bucket, &n)
+		})
+	})
+}
+
+// dumpBucket writes the commands recreating the bucket at path and then,
+// as they come in key order, its pairs and nested buckets. The database is
+// always passed with -db and the arguments follow "--", so neither BOLT_DB
+// nor names starting with "-" change how they are read.
+func (cmd *DumpCommand) dumpBucket(path string, bucket *bolt.Bucket, n *int64) error {
+	fmt.Fprintf(cmd.Stdout, "\"$BOLT\" create-bucket -db \"$DB\" -- %s\n", shellQuote(path))
+	if seq := bucket.Sequence(); seq != 0 {
+		fmt.Fprintf(cmd.Stdout, "\"$BOLT\" set-sequence -db \"$DB\" -- %s %d\n", shellQuote(path), seq)
+	}
+	return bucket.ForEach(func(k, v []byte) error {
+		atomic.AddInt64(n, 1)
+		if v == nil {
+			return cmd.dumpBucket(path+"/"+string(k), bucket.Bucket(k), n)
+		}
+		_, err := fmt.Fprintf(cmd.Stdout, "\"$BOLT\" insert -db \"$DB\" -key-type hex -value-type hex -- %s %x '%x'\n", shellQuote(path), k, v)
+		return err
+	})
+}
+
+// shellQuote wraps s in single quotes so a POSIX shell reads it literally.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func (cmd *DumpCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt dump [-progress] [-rw] PATH
+
+Dump prints a shell script that recreates every bucket, its sequence and
+its key-value pairs using create-bucket, set-sequence and insert. Nested
+buckets are created by their path, such as parent/child, so a bucket name
+containing "/" may not restore to the same place. Keys and values are hex
+encoded so binary data survives the round trip. Very large values (over
+~64KB) may exceed the shell's argument length limit. Restore with:
+
+	bolt dump old.db > dump.sh
+	sh dump.sh new.db
+
+Additional options include:
+
+	-progress
+		Report the number of keys dumped and the elapsed time on
+		stderr every second.
+`, "\n")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type ExistsCommand struct {
+	CommonCommand
+}
+
+func newExistsCommand(m *Main) *ExistsCommand {
+	return &ExistsCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *ExistsCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 2
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	return db.View(func(tx *bolt.Tx) error {
+		bucket, err := boltview.LookupBucket(tx, bucketName)
+		if err == ErrBucketNotFound || errors.Is(err, ErrNotABucket) {
+			return ErrNotExists
+		} else if err != nil {
+			return err
+		}
+		if key != "" && bucket.Get([]byte(key)) == nil {
+			return ErrNotExists
+		}
+		return nil
+	})
+}
+
+func (cmd *ExistsCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt exists PATH BUCKET_NAME [KEY]
+
+Exists prints nothing and exits with status 0 if the bucket (and the key,
+when given) exists, or with status 3 otherwise. It is meant for use in
+shell conditionals:
+
+	if bolt exists my.db users alice; then ...; fi
+`, "\n")
+}
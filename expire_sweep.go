@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type ExpireSweepCommand struct {
+	CommonCommand
+}
+
+func newExpireSweepCommand(m *Main) *ExpireSweepCommand {
+	return &ExpireSweepCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *ExpireSweepCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	swept, err := boltview.SweepExpired(db, bucketName, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, k := range swept {
+		cmd.verbosef("deleted %q\n", k)
+	}
+	fmt.Fprintf(cmd.Stdout, "deleted %d expired keys\n", len(swept))
+	return nil
+}
+
+func (cmd *ExpireSweepCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt expire-sweep PATH BUCKET_NAME
+
+Expire-sweep deletes the keys of the bucket whose expiry, recorded by
+"bolt insert -expire", has passed, along with their entries in the
+companion bucket BUCKET_NAME__exp. Keys without an expiry are kept. A key
+written again without -expire, or deleted, by any bolt command loses its
+expiry, so it isn't swept. It prints the number of keys deleted; -verbose
+also prints each key.
+
+Everything is deleted in a single transaction. Run it from cron or a
+timer to keep the bucket clean; bolt itself never expires keys.
+`, "\n")
+}
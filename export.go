@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+type ExportCommand struct {
+	CommonCommand
+}
+
+func newExportCommand(m *Main) *ExportCommand {
+	return &ExportCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// exportBucket is a bucket in the JSON document written by export.
+type exportBucket struct {
+	Name         string         `json:"name"`
+	NameEncoding string         `json:"name_encoding,omitempty"`
+	Sequence     uint64         `json:"sequence,omitempty"`
+	Pairs        []jsonPair     `json:"pairs"`
+	Buckets      []exportBucket `json:"buckets"`
+}
+
+// Run executes the command.
+func (cmd *ExportCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	help := fs.Bool("h", false, "")
+	out := fs.String("o", "", "")
+	stream := fs.Bool("stream", false, "")
+	gz := fs.Bool("gzip", false, "")
+	maxDepth := fs.Int("max-depth", 100, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), !cmd.rw)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	write := func(w io.Writer) error {
+		return db.View(func(tx *bolt.Tx) error {
+			if *stream {
+				return exportStream(w, tx, *maxDepth)
+			}
+			return exportTree(w, tx, *maxDepth)
+		})
+	}
+	if *gz || strings.HasSuffix(*out, ".gz") {
+		write = gzipped(write)
+	}
+	if *out != "" {
+		// An interrupt stops the export and removes the partial file.
+		ctx, stop := notifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return writeAtomic(ctx, *out, write)
+	}
+	return write(cmd.Stdout)
+}
+
+// checkDepth returns ErrMaxDepth for the bucket at path if depth, the
+// number of levels it is nested below the top level, exceeds maxDepth. A
+// maxDepth of zero or less means no limit.
+func checkDepth(path string, depth, maxDepth int) error {
+	if maxDepth > 0 && depth > maxDepth {
+		return fmt.Errorf("%w: %s", ErrMaxDepth, path)
+	}
+	return nil
+}
+
+// exportTree writes every bucket in tx as a single indented JSON document
+// of the form {"buckets": [...]}. The whole tree is built in memory first.
+func exportTree(w io.Writer, tx *bolt.Tx, maxDepth int) error {
+	var walk func(path string, depth int, name []byte, b *bolt.Bucket) (exportBucket, error)
+	walk = func(path string, depth int, name []byte, b *bolt.Bucket) (exportBucket, error) {
+		e := exportBucket{Sequence: b.Sequence(), Pairs: []jsonPair{}, Buckets: []exportBucket{}}
+		e.Name, e.NameEncoding = jsonText(string(name))
+		if err := checkDepth(path, depth, maxDepth); err != nil {
+			return e, err
+		}
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				child, err := walk(joinPath(path, k), depth+1, k, b.Bucket(k))
+				if err != nil {
+					return e, err
+				}
+				e.Buckets = append(e.Buckets, child)
+				continue
+			}
+			e.Pairs = append(e.Pairs, newJSONPair("", k, v))
+		}
+		return e, nil
+	}
+
+	doc := struct {
+		Buckets []exportBucket `json:"buckets"`
+	}{Buckets: []exportBucket{}}
+	if err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		e, err := walk(string(name), 0, name, b)
+		doc.Buckets = append(doc.Buckets, e)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// exportStream writes one JSON object per line for every entry in tx, as
+// it is read. A bucket is written as an entry of its parent with a null
+// value and "nested": true before its own entries; top-level buckets have
+// no parent bucket.
+func exportStream(w io.Writer, tx *bolt.Tx, maxDepth int) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	var walk func(path string, depth int, b *bolt.Bucket) error
+	walk = func(path string, depth int, b *bolt.Bucket) error {
+		if err := checkDepth(path, depth, maxDepth); err != nil {
+			return err
+		}
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil {
+				if err := enc.Encode(newJSONPair(path, k, v)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := exportNested(enc, path, k, b.Bucket(k)); err != nil {
+				return err
+			} else if err := walk(joinPath(path, k), depth+1, b.Bucket(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if err := exportNested(enc, "", name, b); err != nil {
+			return err
+		}
+		return walk(string(name), 0, b)
+	})
+}
+
+// exportNested writes the entry for the bucket named name in the bucket at
+// path.
+func exportNested(enc *json.Encoder, path string, name []byte, b *bolt.Bucket) error {
+	p := newJSONPair(path, name, nil)
+	p.Sequence = b.Sequence()
+	return enc.Encode(p)
+}
+
+// joinPath returns the path of the bucket name nested in the bucket at path.
+func joinPath(path string, name []byte) string {
+	if path == "" {
+		return string(name)
+	}
+	return path + "/" + string(name)
+}
+
+// gzipped returns a write function that compresses what write writes. The
+// gzip stream is closed only if write succeeds, so a failed export is never
+// a valid archive.
+func gzipped(write func(w io.Writer) error) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if err := write(zw); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+}
+
+// interruptWriter fails every write with ErrInterrupted once ctx is done.
+type interruptWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w interruptWriter) Write(p []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		return 0, ErrInterrupted
+	}
+	return w.w.Write(p)
+}
+
+// writeAtomic calls write with a temporary file in the directory of path
+// and renames it to path only if write succeeds, so a failed export never
+// replaces a good file with a partial one. Once ctx is done, writes fail
+// and the file is not renamed. On failure the temporary file is removed.
+func writeAtomic(ctx context.Context, path string, write func(w io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	// Temporary files are private; give the result the mode of the file it
+	// replaces, or the usual mode of a new file.
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(interruptWriter{ctx, f})
+	if err = write(w); err != nil {
+		return err
+	} else if err = w.Flush(); err != nil {
+		return err
+	} else if ctx.Err() != nil {
+		return ErrInterrupted
+	} else if err = f.Sync(); err != nil {
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (cmd *ExportCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt export [-o FILE] [-stream] [-gzip] [-max-depth N] [-rw] PATH
+
+Export writes every bucket, nested bucket and key-value pair in the
+database as JSON, for backups or for inspecting it with other tools. By
+default it prints a single document that keeps the bucket nesting:
+
+	{"buckets": [{"name": "users", "sequence": 2,
+	              "pairs": [{"key": "alice", "value": "..."}],
+	              "buckets": [...]}]}
+
+The sequence is left out when it is zero. Keys, values and bucket names
+that aren't valid UTF-8 are base64 encoded and marked with "key_encoding",
+"value_encoding" or "name_encoding": "base64".
+
+Additional options include:
+
+	-gzip
+		Compress the output with gzip. This is implied when FILE
+		ends in ".gz". Use "gunzip -c FILE | bolt import PATH" to
+		restore it.
+	-o FILE
+		Write to FILE instead of stdout. The output goes to a
+		temporary file in the same directory that is renamed to FILE
+		only once it is complete, so a failed or interrupted export
+		never replaces an existing FILE. Ctrl-C removes the
+		temporary file and exits with status 130.
+	-max-depth N
+		Fail instead of descending into buckets nested more than N
+		levels deep (default 100). Zero means no limit.
+	-stream
+		Write one JSON object per line as the database is read, so
+		memory use stays flat however large the database is. Each
+		pair is {"bucket": "users", "key": "alice", "value": "..."},
+		where bucket is the path of a nested bucket such as
+		users/settings. Each bucket is written before its contents
+		as an entry of its parent with a null value and
+		"nested": true; top-level buckets have no "bucket".
+`, "\n")
+}
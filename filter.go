@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A filter expression, as given to "list -filter", is checked for syntax and
+// types when it is parsed and compiled into a function of the key and value.
+// The grammar is:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ op operand ]
+//	op      = "==" | "!=" | "<" | "<=" | ">" | ">=" | "startswith" | "contains"
+//	operand = "key" | "value" | "len" "(" or ")" | STRING | NUMBER | "(" or ")"
+//
+// key and value are strings, len returns a number, and a comparison or a
+// logical operator returns a boolean. The whole expression must be boolean.
+
+// Kinds of values in a filter expression.
+const (
+	kindString = "string"
+	kindNumber = "number"
+	kindBool   = "boolean"
+)
+
+// filterExpr is a compiled expression. eval returns a string, an int64 or
+// a bool, as given by kind.
+type filterExpr struct {
+	kind string
+	eval func(k, v []byte) interface{}
+}
+
+// parseFilter compiles s into a scan filter.
+func parseFilter(s string) (func(k, v []byte) bool, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	} else if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	} else if expr.kind != kindBool {
+		return nil, fmt.Errorf("expression is a %s, not a boolean", expr.kind)
+	}
+	return func(k, v []byte) bool { return expr.eval(k, v).(bool) }, nil
+}
+
+// tokenizeFilter splits s into identifiers, numbers, quoted strings and
+// operators. Strings keep their quotes so the parser can tell them apart.
+func tokenizeFilter(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		case isFilterWord(rune(c)):
+			j := i
+			for j < len(s) && isFilterWord(rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, op)
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isFilterWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or "" at the end of the expression.
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// expect consumes the next token, which must be tok.
+func (p *filterParser) expect(tok string) error {
+	if next := p.peek(); next != tok {
+		if next == "" {
+			return fmt.Errorf("expected %q at end of expression", tok)
+		}
+		return fmt.Errorf("expected %q, got %q", tok, next)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) or() (filterExpr, error) {
+	return p.logical("||", p.and)
+}
+
+func (p *filterParser) and() (filterExpr, error) {
+	return p.logical("&&", p.unary)
+}
+
+// logical parses operands joined by op, which is "||" or "&&". The right
+// operand is only evaluated if the left one doesn't decide the result.
+func (p *filterParser) logical(op string, next func() (filterExpr, error)) (filterExpr, error) {
+	left, err := next()
+	if err != nil {
+		return left, err
+	}
+	for p.peek() == op {
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return right, err
+		} else if left.kind != kindBool || right.kind != kindBool {
+			return right, fmt.Errorf("%s needs boolean operands", op)
+		}
+		l, r, decides := left.eval, right.eval, op == "||"
+		left = filterExpr{kind: kindBool, eval: func(k, v []byte) interface{} {
+			if a := l(k, v).(bool); a == decides {
+				return a
+			}
+			return r(k, v).(bool)
+		}}
+	}
+	return left, nil
+}
+
+func (p *filterParser) unary() (filterExpr, error) {
+	if p.peek() != "!" {
+		return p.compare()
+	}
+	p.pos++
+	x, err := p.unary()
+	if err != nil {
+		return x, err
+	} else if x.kind != kindBool {
+		return x, errors.New("! needs a boolean operand")
+	}
+	return filterExpr{kind: kindBool, eval: func(k, v []byte) interface{} { return !x.eval(k, v).(bool) }}, nil
+}
+
+func (p *filterParser) compare() (filterExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return left, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "startswith", "contains":
+	default:
+		return left, nil
+	}
+	p.pos++
+	right, err := p.operand()
+	if err != nil {
+		return right, err
+	} else if left.kind != right.kind {
+		return right, fmt.Errorf("can't compare a %s with a %s", left.kind, right.kind)
+	} else if (op == "startswith" || op == "contains") && left.kind != kindString {
+		return right, fmt.Errorf("%s needs string operands", op)
+	}
+
+	l, r := left.eval, right.eval
+	cmp := func(k, v []byte) int {
+		switch a := l(k, v).(type) {
+		case string:
+			return strings.Compare(a, r(k, v).(string))
+		case int64:
+			if b := r(k, v).(int64); a < b {
+				return -1
+			} else if a > b {
+				return 1
+			}
+			return 0
+		case bool:
+			// Booleans are only equal or not.
+			if a == r(k, v).(bool) {
+				return 0
+			}
+			return 1
+		}
+		return 0
+	}
+	var eval func(k, v []byte) interface{}
+	switch op {
+	case "startswith":
+		eval = func(k, v []byte) interface{} { return strings.HasPrefix(l(k, v).(string), r(k, v).(string)) }
+	case "contains":
+		eval = func(k, v []byte) interface{} { return strings.Contains(l(k, v).(string), r(k, v).(string)) }
+	case "==":
+		eval = func(k, v []byte) interface{} { return cmp(k, v) == 0 }
+	case "!=":
+		eval = func(k, v []byte) interface{} { return cmp(k, v) != 0 }
+	default:
+		if left.kind == kindBool {
+			return right, fmt.Errorf("%s needs string or number operands", op)
+		}
+		eval = map[string]func(k, v []byte) interface{}{
+			"<":  func(k, v []byte) interface{} { return cmp(k, v) < 0 },
+			"<=": func(k, v []byte) interface{} { return cmp(k, v) <= 0 },
+			">":  func(k, v []byte) interface{} { return cmp(k, v) > 0 },
+			">=": func(k, v []byte) interface{} { return cmp(k, v) >= 0 },
+		}[op]
+	}
+	return filterExpr{kind: kindBool, eval: eval}, nil
+}
+
+func (p *filterParser) operand() (filterExpr, error) {
+	tok := p.peek()
+	p.pos++
+	switch {
+	case tok == "":
+		return filterExpr{}, errors.New("unexpected end of expression")
+	case tok == "key":
+		return filterExpr{kind: kindString, eval: func(k, v []byte) interface{} { return string(k) }}, nil
+	case tok == "value":
+		return filterExpr{kind: kindString, eval: func(k, v []byte) interface{} { return string(v) }}, nil
+	case tok == "len":
+		if err := p.expect("("); err != nil {
+			return filterExpr{}, err
+		}
+		x, err := p.or()
+		if err != nil {
+			return x, err
+		} else if err := p.expect(")"); err != nil {
+			return x, err
+		} else if x.kind != kindString {
+			return x, fmt.Errorf("len needs a string, not a %s", x.kind)
+		}
+		return filterExpr{kind: kindNumber, eval: func(k, v []byte) interface{} { return int64(len(x.eval(k, v).(string))) }}, nil
+	case tok == "(":
+		x, err := p.or()
+		if err != nil {
+			return x, err
+		}
+		return x, p.expect(")")
+	case tok[0] == '"':
+		s, err := strconv.Unquote(tok)
+		if err != nil {
+			return filterExpr{}, fmt.Errorf("invalid string %s", tok)
+		}
+		return filterExpr{kind: kindString, eval: func(k, v []byte) interface{} { return s }}, nil
+	case unicode.IsDigit(rune(tok[0])):
+		n, err := strconv.ParseInt(tok, 10, 64)
+		if err != nil {
+			return filterExpr{}, fmt.Errorf("invalid number %s", tok)
+		}
+		return filterExpr{kind: kindNumber, eval: func(k, v []byte) interface{} { return n }}, nil
+	}
+	return filterExpr{}, fmt.Errorf("unexpected %q", tok)
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestParseFilter(t *testing.T) {
+	for _, tt := range []struct {
+		expr  string
+		key   string
+		value string
+		want  bool
+	}{
+		{`len(value) > 3`, "k", "abcd", true},
+		{`len(value) > 3`, "k", "abc", false},
+		{`key startswith "user:"`, "user:1", "", true},
+		{`key startswith "user:"`, "group:1", "", false},
+		{`value contains "x" && len(key) == 2`, "ab", "xyz", true},
+		{`value contains "x" && len(key) == 2`, "abc", "xyz", false},
+		{`key == "a" || key == "b"`, "b", "", true},
+		{`!(key < "m")`, "n", "", true},
+		{`key != "it's"`, "it's", "", false},
+	} {
+		match, err := parseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %s", tt.expr, err)
+		} else if got := match([]byte(tt.key), []byte(tt.value)); got != tt.want {
+			t.Errorf("%s on %q=%q: got %v, want %v", tt.expr, tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilter_Error(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`key`,
+		`len(value)`,
+		`len(value) > "3"`,
+		`key startswith`,
+		`key == "a`,
+		`(key == "a"`,
+		`key == "a" key`,
+		`size > 3`,
+	} {
+		if _, err := parseFilter(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+type FindCommand struct {
+	CommonCommand
+}
+
+func newFindCommand(m *Main) *FindCommand {
+	return &FindCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *FindCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	byValue := fs.Bool("value", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	needle := []byte(cmd.arg(fs, 0))
+	if len(needle) == 0 {
+		if *byValue {
+			return ErrValueRequired
+		}
+		return ErrKeyRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	return db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if !*byValue {
+				if v := bucket.Get(needle); v != nil {
+					fmt.Fprintf(cmd.Stdout, "%s\t%s\n", name, v)
+				}
+				return nil
+			}
+
+			// Searching by value requires a full scan of every bucket.
+			return bucket.ForEach(func(k, v []byte) error {
+				if v != nil && bytes.Equal(v, needle) {
+					fmt.Fprintf(cmd.Stdout, "%s\t%s\n", name, k)
+				}
+				return nil
+			})
+		})
+	})
+}
+
+func (cmd *FindCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt find [-value] PATH KEY
+
+Find looks up KEY in every top-level bucket and prints a
+"bucket<TAB>value" line for each bucket that contains it.
+
+Additional options include:
+
+	-value
+		Treat the argument as a value instead and print a
+		"bucket<TAB>key" line for every key whose value equals it.
+		This scans the whole database.
+`, "\n")
+}
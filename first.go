@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+// FirstCommand prints the first or, if last is set, the last pair of a
+// bucket.
+type FirstCommand struct {
+	CommonCommand
+	last bool
+}
+
+func newFirstCommand(m *Main) *FirstCommand {
+	return &FirstCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+func newLastCommand(m *Main) *FirstCommand {
+	cmd := newFirstCommand(m)
+	cmd.last = true
+	return cmd
+}
+
+// Run executes the command.
+func (cmd *FirstCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	keyType := fs.String("key-type", typeString, "")
+	valueType := fs.String("value-type", typeString, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := checkType(*keyType); err != nil {
+		return err
+	} else if err := checkType(*valueType); err != nil {
+		return err
+	}
+
+	cmd.maxArgs = 1
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	boundary := boltview.First
+	if cmd.last {
+		boundary = boltview.Last
+	}
+	pair, err := boundary(db, bucketName)
+	if err != nil {
+		return err
+	} else if pair.Key == nil {
+		// An empty bucket has no boundary pair.
+		return nil
+	}
+	fmt.Fprintf(cmd.Stdout, "%s\t%s\n", decodeType(*keyType, pair.Key), decodeType(*valueType, pair.Value))
+	return nil
+}
+
+func (cmd *FirstCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt first [-key-type TYPE] [-value-type TYPE] PATH BUCKET_NAME
+       bolt last [-key-type TYPE] [-value-type TYPE] PATH BUCKET_NAME
+
+First prints the pair with the smallest key in the bucket as
+"key<TAB>value"; last prints the pair with the largest key. Only the
+boundary key is read, so this is cheap even on large buckets. Nothing is
+printed for an empty bucket.
+
+Additional options include:
+
+	-key-type TYPE
+		Decode the key as TYPE for display: string (the default),
+		hex, base64, uint32be or uint64be.
+	-value-type TYPE
+		Decode the value as TYPE for display.
+`, "\n")
+}
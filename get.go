@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type GetCommand struct {
+	CommonCommand
+}
+
+func newGetCommand(m *Main) *GetCommand {
+	return &GetCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *GetCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	help := fs.Bool("h", false, "")
+	skipMissing := fs.Bool("skip-missing", false, "")
+	defaultValue := fs.String("default", "", "")
+	decode := fs.String("decode", "none", "")
+	pretty := fs.Bool("pretty", false, "")
+	keyType := fs.String("key-type", typeString, "")
+	valueType := fs.String("value-type", typeString, "")
+	fs.Var(encodingFlag{keyType}, "key-encoding", "")
+	fs.Var(encodingFlag{valueType}, "value-encoding", "")
+	formatKey := fs.String("format-key", "", "")
+	formatValue := fs.String("format-value", "", "")
+	format := fs.String("format", outputText, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := checkOutput(*format); err != nil {
+		return err
+	} else if err := exclusive(fs, "format-key", "key-type", "key-encoding"); err != nil {
+		return err
+	} else if err := exclusive(fs, "format-value", "value-type", "pretty", "value-encoding"); err != nil {
+		return err
+	}
+
+	cmd.maxArgs = 2
+
+	// -format-key and -format-value pick the key and value encoding
+	// independently, in place of -key-type, -value-type and -pretty.
+	var err error
+	if *formatKey != "" {
+		if *keyType, _, err = parseFormat(*formatKey, false); err != nil {
+			return err
+		}
+	}
+	if *formatValue != "" {
+		if *valueType, *pretty, err = parseFormat(*formatValue, true); err != nil {
+			return err
+		}
+	}
+
+	if err := checkType(*keyType); err != nil {
+		return err
+	} else if err := checkType(*valueType); err != nil {
+		return err
+	} else if err := exclusive(fs, "skip-missing", "default"); err != nil {
+		return err
+	} else if err := checkDecode(*decode); err != nil {
+		return err
+	}
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+	hasDefault := isSet(fs, "default")
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), !cmd.rw)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	// display decodes a stored value for printing.
+	display := func(k, value []byte) string {
+		value = cmd.decodeValue(*decode, k, value)
+		if *pretty {
+			value = prettyJSON(value)
+		}
+		return decodeType(*valueType, value)
+	}
+
+	// With -format json every result is an object on its own line, and a
+	// missing key has a null value.
+	enc := json.NewEncoder(cmd.Stdout)
+	enc.SetEscapeHTML(false)
+	printJSON := func(key string, value *string) error {
+		p := jsonPair{Bucket: bucketName}
+		p.Key, p.KeyEncoding = jsonText(key)
+		if value != nil {
+			var v string
+			v, p.ValueEncoding = jsonText(*value)
+			p.Value = &v
+		}
+		return enc.Encode(p)
+	}
+
+	// Print a single value when the key is given.
+	if key != "" {
+		k, err := encodeType(*keyType, key)
+		if err != nil {
+			return fmt.Errorf("invalid %s key: %s", *keyType, err)
+		}
+		value, err := boltview.Get(db, bucketName, k)
+		if err == ErrKeyNotFound && hasDefault {
+			if *format == outputJSON {
+				return printJSON(key, defaultValue)
+			}
+			fmt.Fprintln(cmd.Stdout, *defaultValue)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		s := display(k, value)
+		if *format == outputJSON {
+			return printJSON(key, &s)
+		}
+		fmt.Fprintln(cmd.Stdout, s)
+		return nil
+	}
+
+	// Otherwise look up every key read from stdin in a single transaction.
+	return db.View(func(tx *bolt.Tx) error {
+		bucket, err := boltview.LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(cmd.Stdin)
+		for scanner.Scan() {
+			k := scanner.Text()
+			if k == "" {
+				continue
+			}
+			raw, err := encodeType(*keyType, k)
+			if err != nil {
+				return fmt.Errorf("invalid %s key %q: %s", *keyType, k, err)
+			}
+
+			var value *string
+			if v := bucket.Get(raw); v != nil {
+				s := display(raw, v)
+				value = &s
+			} else if hasDefault {
+				value = defaultValue
+			} else if *skipMissing {
+				continue
+			}
+
+			if *format == outputJSON {
+				err = printJSON(k, value)
+			} else if value == nil {
+				_, err = fmt.Fprintf(cmd.Stdout, "%s\t<null>\n", k)
+			} else {
+				_, err = fmt.Fprintf(cmd.Stdout, "%s\t%s\n", k, *value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	})
+}
+
+func (cmd *GetCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt get [-skip-missing | -default VALUE] [-decode gzip] [-pretty] [-rw]
+                [-key-type TYPE] [-value-type TYPE] [-key-encoding ENCODING]
+                [-value-encoding ENCODING] [-format-key FORMAT]
+                [-format-value FORMAT] [-format FORMAT] PATH BUCKET_NAME [KEY]
+
+Get prints the value of KEY in the bucket. If no KEY is given, keys are
+read one per line from stdin and printed as "key<TAB>value" pairs, all
+within a single read transaction.
+
+Additional options include:
+
+	-skip-missing
+		When reading keys from stdin, omit keys that do not exist
+		instead of printing them with a <null> value.
+	-default VALUE
+		Print VALUE for a missing key and exit with status 0 instead
+		of failing with "key not found". Keys read from stdin are
+		printed with VALUE instead of <null>. VALUE is printed as
+		given, regardless of -value-type.
+	-decode gzip
+		Gunzip the value before printing it. A value that isn't gzip
+		compressed is printed as stored, with a warning on stderr.
+	-pretty
+		Indent the value if it is valid JSON. Other values are
+		printed as stored.
+	-key-type TYPE
+		Encode KEY (and keys read from stdin) as TYPE before the
+		lookup: string (the default), hex, base64, uint32be or
+		uint64be.
+	-value-type TYPE
+		Decode the value as TYPE for display, e.g. -key-type hex 00ff
+		-value-type base64 so binary keys and values survive the
+		shell.
+	-key-encoding ENCODING, -value-encoding ENCODING
+		Set -key-type or -value-type to raw (string), hex or base64.
+	-format FORMAT
+		Print plain text (the default) or a JSON object per key,
+		such as {"bucket":"b","key":"k","value":"v"}, e.g. for jq. A
+		missing key read from stdin has a null value. A value that
+		isn't valid UTF-8 is base64 encoded and marked with
+		"value_encoding": "base64".
+	-format-key FORMAT, -format-value FORMAT
+		Choose the key and value encodings independently: raw, hex,
+		base64, uint32be, uint64be or, for the value only,
+		json-pretty. These replace -key-type, -value-type and
+		-pretty and can't be combined with them.
+`, "\n")
+}
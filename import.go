@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type ImportCommand struct {
+	CommonCommand
+
+	// Conflict handling for keys that already exist, set by Run.
+	overwrite    bool
+	skipExisting bool
+
+	// Counts reported when done.
+	imported, skipped int
+}
+
+func newImportCommand(m *Main) *ImportCommand {
+	return &ImportCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *ImportCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	fs.BoolVar(&cmd.overwrite, "overwrite", false, "")
+	fs.BoolVar(&cmd.skipExisting, "skip-existing", false, "")
+	fs.Bool("fail", false, "")
+	batchSize := fs.Int("batch-size", 0, "")
+	noSync := fs.Bool("no-sync", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := exclusive(fs, "overwrite", "skip-existing", "fail"); err != nil {
+		return err
+	} else if *batchSize < 0 {
+		return errors.New("-batch-size must not be negative")
+	}
+
+	cmd.maxArgs = 1
+
+	// Read the export from FILE, or from stdin if it is omitted or "-".
+	var r io.Reader = cmd.Stdin
+	if name := cmd.arg(fs, 0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	next, err := readExport(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	db.NoSync = *noSync
+
+	// The export is decoded while writing, so by default everything
+	// happens in one transaction and a conflict or malformed input rolls
+	// all of it back.
+	if _, err := updateBatches(db, *batchSize, func(tx *bolt.Tx) error {
+		e, err := next()
+		if err != nil {
+			return err
+		}
+		return cmd.apply(tx, e)
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Stdout, "imported %d keys, skipped %d existing keys\n", cmd.imported, cmd.skipped)
+	return nil
+}
+
+// importEntry is a bucket or a key-value pair read from an export.
+type importEntry struct {
+	parent   [][]byte // names of the enclosing buckets, outermost first
+	key      []byte
+	value    []byte
+	nested   bool
+	sequence uint64
+	where    string // prefixed to errors, e.g. "line 3"
+}
+
+// readExport returns an iterator over the entries of either format written
+// by export: a single document with a "buckets" member, or one entry per
+// line as written by -stream. A document is decoded up front; a stream is
+// decoded as it is read. The iterator returns io.EOF at the end.
+func readExport(r io.Reader) (func() (importEntry, error), error) {
+	dec := json.NewDecoder(r)
+	var first json.RawMessage
+	if err := dec.Decode(&first); err == io.EOF {
+		return func() (importEntry, error) { return importEntry{}, io.EOF }, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid json: %s", err)
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(first, &probe); err != nil {
+		return nil, fmt.Errorf("invalid json: %s", err)
+	}
+	if _, ok := probe["buckets"]; ok {
+		var doc struct {
+			Buckets []exportBucket `json:"buckets"`
+		}
+		if err := json.Unmarshal(first, &doc); err != nil {
+			return nil, fmt.Errorf("invalid json: %s", err)
+		}
+		var entries []importEntry
+		for _, b := range doc.Buckets {
+			if err := flattenBucket(&entries, nil, b); err != nil {
+				return nil, err
+			}
+		}
+		return func() (importEntry, error) {
+			if len(entries) == 0 {
+				return importEntry{}, io.EOF
+			}
+			e := entries[0]
+			entries = entries[1:]
+			return e, nil
+		}, nil
+	}
+
+	// Each stream entry names the bucket it belongs to by path. Buckets
+	// are written before their contents, so remember the names along each
+	// path instead of splitting it, which would be ambiguous for names
+	// containing "/".
+	paths := make(map[string][][]byte)
+	line := 0
+	return func() (importEntry, error) {
+		var p jsonPair
+		if line == 0 {
+			if err := json.Unmarshal(first, &p); err != nil {
+				return importEntry{}, fmt.Errorf("line 1: invalid json: %s", err)
+			}
+		} else if err := dec.Decode(&p); err == io.EOF {
+			return importEntry{}, io.EOF
+		} else if err != nil {
+			return importEntry{}, fmt.Errorf("line %d: invalid json: %s", line+1, err)
+		}
+		line++
+		e, err := streamEntry(paths, p)
+		if err != nil {
+			return importEntry{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		e.where = fmt.Sprintf("line %d", line)
+		return e, nil
+	}, nil
+}
+
+// streamEntry decodes one line of an -stream export.
+func streamEntry(paths map[string][][]byte, p jsonPair) (importEntry, error) {
+	path, err := decodeJSONText(p.Bucket, p.BucketEncoding)
+	if err != nil {
+		return importEntry{}, fmt.Errorf("invalid bucket: %s", err)
+	}
+	key, err := decodeJSONText(p.Key, p.KeyEncoding)
+	if err != nil {
+		return importEntry{}, fmt.Errorf("invalid key: %s", err)
+	} else if len(key) == 0 {
+		return importEntry{}, ErrKeyRequired
+	}
+
+	parent, ok := paths[string(path)]
+	if !ok && len(path) > 0 {
+		return importEntry{}, fmt.Errorf("%w: %s", ErrBucketNotFound, path)
+	}
+
+	if p.Nested {
+		paths[joinPath(string(path), key)] = append(append([][]byte{}, parent...), key)
+		return importEntry{parent: parent, key: key, nested: true, sequence: p.Sequence}, nil
+	}
+
+	if parent == nil {
+		return importEntry{}, errors.New("pair outside of a bucket")
+	} else if p.Value == nil {
+		return importEntry{}, ErrValueRequired
+	}
+	value, err := decodeJSONText(*p.Value, p.ValueEncoding)
+	if err != nil {
+		return importEntry{}, fmt.Errorf("invalid value: %s", err)
+	}
+	return importEntry{parent: parent, key: key, value: value}, nil
+}
+
+// flattenBucket appends the bucket e inside parent, its pairs and its
+// nested buckets to entries, in that order.
+func flattenBucket(entries *[]importEntry, parent [][]byte, e exportBucket) error {
+	name, err := decodeJSONText(e.Name, e.NameEncoding)
+	if err != nil {
+		return fmt.Errorf("invalid bucket name %q: %s", e.Name, err)
+	} else if len(name) == 0 {
+		return ErrBucketRequired
+	}
+	where := fmt.Sprintf("bucket %s", name)
+	*entries = append(*entries, importEntry{parent: parent, key: name, nested: true, sequence: e.Sequence, where: where})
+
+	path := append(append([][]byte{}, parent...), name)
+	for _, p := range e.Pairs {
+		key, err := decodeJSONText(p.Key, p.KeyEncoding)
+		if err != nil {
+			return fmt.Errorf("bucket %s: invalid key %q: %s", name, p.Key, err)
+		} else if len(key) == 0 {
+			return fmt.Errorf("bucket %s: %w", name, ErrKeyRequired)
+		} else if p.Value == nil {
+			return fmt.Errorf("bucket %s: key %q: %w", name, p.Key, ErrValueRequired)
+		}
+		value, err := decodeJSONText(*p.Value, p.ValueEncoding)
+		if err != nil {
+			return fmt.Errorf("bucket %s: invalid value of %q: %s", name, p.Key, err)
+		}
+		*entries = append(*entries, importEntry{parent: path, key: key, value: value, where: where})
+	}
+	for _, child := range e.Buckets {
+		if err := flattenBucket(entries, path, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply writes e within tx, creating the bucket, or merging into it if it
+// exists, or storing the pair.
+func (cmd *ImportCommand) apply(tx *bolt.Tx, e importEntry) error {
+	var parent *bolt.Bucket
+	for i, name := range e.parent {
+		if i == 0 {
+			parent = tx.Bucket(name)
+		} else {
+			parent = parent.Bucket(name)
+		}
+		if parent == nil {
+			return fmt.Errorf("%s: %w", e.where, ErrBucketNotFound)
+		}
+	}
+
+	var err error
+	if e.nested {
+		var b *bolt.Bucket
+		if b, err = createNested(tx, parent, e.key); err == nil {
+			err = setSequence(b, e.sequence)
+		}
+	} else {
+		err = cmd.put(parent, e.key, e.value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", e.where, err)
+	}
+	return nil
+}
+
+// put stores the pair unless the key exists, in which case it is
+// overwritten, skipped or an error depending on the conflict flags.
+func (cmd *ImportCommand) put(b *bolt.Bucket, key, value []byte) error {
+	if b.Bucket(key) != nil {
+		return fmt.Errorf("%q %w", key, boltview.ErrNotAKey)
+	} else if b.Get(key) != nil && !cmd.overwrite {
+		if !cmd.skipExisting {
+			return fmt.Errorf("%w: %q", ErrKeyExists, key)
+		}
+		cmd.skipped++
+		return nil
+	}
+	cmd.imported++
+	return b.Put(key, value)
+}
+
+// createNested returns the bucket name in parent, or at the top level if
+// parent is nil, creating it if needed.
+func createNested(tx *bolt.Tx, parent *bolt.Bucket, name []byte) (*bolt.Bucket, error) {
+	if parent == nil {
+		return tx.CreateBucketIfNotExists(name)
+	}
+	return parent.CreateBucketIfNotExists(name)
+}
+
+// setSequence raises the sequence of b to n. It is never lowered, so ids
+// already handed out by an existing bucket aren't reused.
+func setSequence(b *bolt.Bucket, n uint64) error {
+	if n <= b.Sequence() {
+		return nil
+	}
+	return b.SetSequence(n)
+}
+
+// decodeJSONText reverses jsonText.
+func decodeJSONText(s, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(s), nil
+	case typeBase64:
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+func (cmd *ImportCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt import [-overwrite | -skip-existing | -fail] [-batch-size N]
+                   [-no-sync] PATH [FILE]
+
+Import reads JSON written by "bolt export" from FILE, or from stdin if FILE
+is omitted or "-", and recreates its buckets, nested buckets and key-value
+pairs in the database. Both the single document and the -stream format are
+accepted. Buckets that already exist are merged into. A bucket's sequence
+is raised to the exported one but never lowered.
+
+By default everything is imported in a single transaction: if anything
+fails, nothing is written. With -batch-size the import commits every N
+entries instead, which bounds the memory a large import needs, but a
+failure leaves the batches before it written. It prints the number of keys
+imported and skipped.
+
+	bolt export -o backup.json old.db
+	bolt import -touch new.db backup.json
+
+Additional options include:
+
+	-batch-size N
+		Commit after every N buckets and pairs rather than once at
+		the end.
+	-no-sync
+		Skip the fsync after each commit. This speeds up loading a
+		throwaway database but a crash can lose or corrupt data, so
+		never use it on production data.
+	-fail
+		Fail with "key already exists" if a key is already in the
+		database. This is the default.
+	-overwrite
+		Replace the values of keys that already exist.
+	-skip-existing
+		Keep the values of keys that already exist and import only
+		the new keys.
+`, "\n")
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+// Input formats read by load.
+const (
+	loadNDJSON = "ndjson"
+	loadCSV    = "csv"
+	loadTSV    = "tsv"
+)
+
+type LoadCommand struct {
+	CommonCommand
+
+	// Set by Run.
+	keyType   string
+	valueType string
+}
+
+func newLoadCommand(m *Main) *LoadCommand {
+	return &LoadCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *LoadCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	format := fs.String("format", "", "")
+	header := fs.Bool("header", false, "")
+	fs.StringVar(&cmd.keyType, "key-type", typeString, "")
+	fs.StringVar(&cmd.valueType, "value-type", typeString, "")
+	batchSize := fs.Int("batch-size", 1000, "")
+	noSync := fs.Bool("no-sync", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := checkType(cmd.keyType); err != nil {
+		return err
+	} else if err := checkType(cmd.valueType); err != nil {
+		return err
+	} else if *batchSize <= 0 {
+		return errors.New("-batch-size must be positive")
+	}
+
+	cmd.maxArgs = 2
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Read from FILE, or from stdin if it is omitted or "-". Without
+	// -format the format follows the file's extension.
+	var r io.Reader = cmd.Stdin
+	name := cmd.arg(fs, 1)
+	if name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	if *format == "" {
+		*format = loadFormat(name)
+	}
+
+	var next func() (boltview.Pair, error)
+	switch *format {
+	case loadNDJSON:
+		next = cmd.readNDJSON(r)
+	case loadCSV:
+		next = cmd.readCSV(r, *header)
+	case loadTSV:
+		next = cmd.readTSV(r, *header)
+	default:
+		return fmt.Errorf("unknown format %q: must be ndjson, csv or tsv", *format)
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	db.NoSync = *noSync
+
+	n, err := putBatches(db, bucketName, *batchSize, next)
+	fmt.Fprintf(cmd.Stdout, "loaded %d pairs\n", n)
+	return err
+}
+
+// loadFormat returns the input format for the file name, defaulting to
+// ndjson.
+func loadFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return loadCSV
+	case ".tsv", ".tab":
+		return loadTSV
+	}
+	return loadNDJSON
+}
+
+// putBatches writes the pairs returned by next, until it returns io.EOF,
+// to the bucket in transactions of batchSize pairs, creating the bucket if
+// needed. A failure stops the load but keeps the batches before it. It
+// returns the number of pairs written.
+func putBatches(db *bolt.DB, bucketName string, batchSize int, next func() (boltview.Pair, error)) (int, error) {
+	return updateBatches(db, batchSize, func(tx *bolt.Tx) error {
+		bucket, err := boltview.CreateBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		p, err := next()
+		if err != nil {
+			return err
+		} else if err := bucket.Put(p.Key, p.Value); err != nil {
+			return err
+		}
+		return boltview.ClearExpiry(tx, bucketName, p.Key)
+	})
+}
+
+// updateBatches calls write until it returns io.EOF, committing after every
+// batchSize calls, or only at the end if batchSize is zero. A failure rolls
+// back the current batch but keeps the ones before it. It returns the
+// number of writes committed.
+func updateBatches(db *bolt.DB, batchSize int, write func(tx *bolt.Tx) error) (int, error) {
+	var n int
+	for done := false; !done; {
+		var pending int
+		err := db.Update(func(tx *bolt.Tx) error {
+			for batchSize == 0 || pending < batchSize {
+				if err := write(tx); err == io.EOF {
+					done = true
+					return nil
+				} else if err != nil {
+					return err
+				}
+				pending++
+			}
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+		n += pending
+	}
+	return n, nil
+}
+
+// pair encodes the key and value text as -key-type and -value-type.
+func (cmd *LoadCommand) pair(key, value string) (boltview.Pair, error) {
+	k, err := encodeKey(cmd.keyType, key)
+	if err != nil {
+		return boltview.Pair{}, err
+	}
+	v, err := cmd.value(value)
+	if err != nil {
+		return boltview.Pair{}, err
+	}
+	return boltview.Pair{Key: k, Value: v}, nil
+}
+
+// value encodes the value text as -value-type.
+func (cmd *LoadCommand) value(text string) ([]byte, error) {
+	v, err := encodeType(cmd.valueType, text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value: %s", cmd.valueType, err)
+	}
+	return v, nil
+}
+
+// readNDJSON reads one {"key": ..., "value": ...} object per line, as
+// printed by "list -format json". A value that isn't a string is stored as
+// compact JSON. Entries for nested buckets are skipped.
+func (cmd *LoadCommand) readNDJSON(r io.Reader) func() (boltview.Pair, error) {
+	dec := json.NewDecoder(r)
+	line := 0
+	return func() (boltview.Pair, error) {
+		for {
+			line++
+			var e struct {
+				Key           string          `json:"key"`
+				KeyEncoding   string          `json:"key_encoding"`
+				Value         json.RawMessage `json:"value"`
+				ValueEncoding string          `json:"value_encoding"`
+				Nested        bool            `json:"nested"`
+			}
+			if err := dec.Decode(&e); err == io.EOF {
+				return boltview.Pair{}, err
+			} else if err != nil {
+				return boltview.Pair{}, fmt.Errorf("line %d: invalid json: %s", line, err)
+			} else if e.Nested {
+				continue
+			} else if len(e.Value) == 0 || string(e.Value) == "null" {
+				return boltview.Pair{}, fmt.Errorf("line %d: %w", line, ErrValueRequired)
+			}
+
+			// A value that isn't a plain string is kept as compact JSON.
+			var value string
+			if e.Value[0] != '"' {
+				var buf bytes.Buffer
+				if err := json.Compact(&buf, e.Value); err != nil {
+					return boltview.Pair{}, fmt.Errorf("line %d: invalid json: %s", line, err)
+				}
+				value = buf.String()
+			} else if err := json.Unmarshal(e.Value, &value); err != nil {
+				return boltview.Pair{}, fmt.Errorf("line %d: invalid json: %s", line, err)
+			}
+
+			// A field marked with an encoding decodes to the stored bytes;
+			// the other fields are decoded as -key-type and -value-type.
+			var k, v []byte
+			var err error
+			if e.KeyEncoding != "" {
+				k, err = decodeJSONText(e.Key, e.KeyEncoding)
+			} else {
+				k, err = encodeKey(cmd.keyType, e.Key)
+			}
+			if err != nil {
+				return boltview.Pair{}, fmt.Errorf("line %d: invalid key: %s", line, err)
+			}
+			if e.ValueEncoding != "" {
+				if v, err = decodeJSONText(value, e.ValueEncoding); err != nil {
+					err = fmt.Errorf("invalid value: %s", err)
+				}
+			} else {
+				v, err = cmd.value(value)
+			}
+			if err != nil {
+				return boltview.Pair{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			return boltview.Pair{Key: k, Value: v}, nil
+		}
+	}
+}
+
+// readCSV reads "key,value" records.
+func (cmd *LoadCommand) readCSV(r io.Reader, header bool) func() (boltview.Pair, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	return func() (boltview.Pair, error) {
+		if header {
+			header = false
+			if _, err := cr.Read(); err != nil {
+				return boltview.Pair{}, err
+			}
+		}
+		record, err := cr.Read()
+		if err != nil {
+			return boltview.Pair{}, err
+		}
+		p, err := cmd.pair(record[0], record[1])
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return p, fmt.Errorf("line %d: %w", line, err)
+		}
+		return p, nil
+	}
+}
+
+// readTSV reads "key<TAB>value" lines. The value is the rest of the line,
+// so it may contain tabs. Empty lines are skipped.
+func (cmd *LoadCommand) readTSV(r io.Reader, header bool) func() (boltview.Pair, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	line := 0
+	return func() (boltview.Pair, error) {
+		for scanner.Scan() {
+			line++
+			text := scanner.Text()
+			if text == "" || header && line == 1 {
+				continue
+			}
+			i := strings.IndexByte(text, '\t')
+			if i < 0 {
+				return boltview.Pair{}, fmt.Errorf("line %d: expected KEY<TAB>VALUE", line)
+			}
+			p, err := cmd.pair(text[:i], text[i+1:])
+			if err != nil {
+				return p, fmt.Errorf("line %d: %w", line, err)
+			}
+			return p, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return boltview.Pair{}, err
+		}
+		return boltview.Pair{}, io.EOF
+	}
+}
+
+func (cmd *LoadCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt load [-format FORMAT] [-header] [-key-type TYPE]
+                 [-value-type TYPE] [-batch-size N] [-no-sync]
+                 PATH BUCKET_NAME [FILE]
+
+Load reads key-value pairs from FILE, or from stdin if FILE is omitted or
+"-", and stores them in the bucket, creating the bucket if needed. The
+database is opened once and the pairs are written in batches of
+-batch-size pairs, one transaction per batch, which is far faster than
+running "bolt insert" for each pair. If a line is malformed the batches
+before it stay written. It prints the number of pairs loaded. An existing
+value for a key is overwritten. "bolt import-csv" is the same as
+"bolt load -format csv".
+
+The input formats are:
+
+	ndjson  one {"key": "k", "value": "v"} object per line, as printed
+	        by "bolt list -format json". A value that isn't a string is
+	        stored as compact JSON. Keys and values marked with
+	        "key_encoding" or "value_encoding": "base64" are decoded,
+	        the others follow -key-type and -value-type, and entries
+	        for nested buckets are skipped.
+	csv     "key,value" records
+	tsv     "key<TAB>value" lines; the value is the rest of the line
+
+Additional options include:
+
+	-format FORMAT
+		The input format: ndjson, csv or tsv. By default it follows
+		the extension of FILE (.csv, .tsv or .tab) and is ndjson
+		otherwise, including for stdin.
+	-header
+		Skip the first csv record or tsv line, which holds column
+		names.
+	-key-type TYPE, -value-type TYPE
+		Decode keys or values as TYPE: string (the default), hex,
+		base64, uint32be or uint64be. Use hex or base64 for binary
+		data.
+	-batch-size N
+		Commit after every N pairs (default 1000).
+	-no-sync
+		Skip the fsync after each commit. This speeds up loading a
+		throwaway database but a crash can lose or corrupt data, so
+		never use it on production data.
+`, "\n")
+}
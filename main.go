@@ -1,14 +1,28 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
 )
 
 var (
@@ -19,19 +33,76 @@ var (
 	ErrBucketRequired = errors.New("bucket required")
 	ErrKeyRequired    = errors.New("key required")
 	ErrValueRequired  = errors.New("value required")
+	ErrShellRequired  = errors.New("shell required")
+	ErrValueConflict  = errors.New("value argument and -in are mutually exclusive")
 
 	ErrFileNotFound   = errors.New("file not found")
-	ErrBucketNotFound = errors.New("bucket not found")
+	ErrStdinWrite     = errors.New("cannot modify a database read from stdin")
+	ErrBucketNotFound = boltview.ErrBucketNotFound
+	ErrKeyNotFound    = boltview.ErrKeyNotFound
+	ErrNoBuckets      = errors.New("no buckets")
+	ErrKeyExists      = boltview.ErrKeyExists
+	ErrMaxDepth       = boltview.ErrMaxDepth
+	ErrNotABucket     = boltview.ErrNotABucket
+	ErrNotExists      = errors.New("does not exist")
+	ErrCondition      = errors.New("condition not met")
+	ErrLocked         = errors.New("database is locked")
+	ErrNotConfirmed   = errors.New("not confirmed")
+	ErrInterrupted    = errors.New("interrupted")
 )
 
+// notifyContext is signal.NotifyContext. Commands that write files use it
+// to catch an interrupt and clean up instead of dying part way through;
+// tests replace it to simulate one.
+var notifyContext = signal.NotifyContext
+
 func main() {
 	m := NewMain()
-	if err := m.Run(os.Args[1:]...); err == ErrUsage {
-		os.Exit(2)
-	} else if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+	os.Exit(m.report(m.Run(os.Args[1:]...)))
+}
+
+// report prints the error returned by Run, if it comes with a message, and
+// returns the exit status for it.
+func (m *Main) report(err error) int {
+	code := exitCode(err)
+	if err != nil && m.JSONErrors {
+		// Scripts get every failure, including the quiet ones, as JSON.
+		b, _ := json.Marshal(struct {
+			Error string `json:"error"`
+			Code  int    `json:"code"`
+		}{err.Error(), code})
+		fmt.Fprintln(m.Stderr, string(b))
+	} else if code == 1 || code == 5 && err != ErrLocked {
+		// A lock timeout keeps its status but still explains itself;
+		// only check-lock's bare ErrLocked is quiet.
+		fmt.Fprintln(m.Stdout, err.Error())
+	}
+	return code
+}
+
+// exitCode returns the exit status for the error returned by Main.Run.
+// Only status 1 and lock timeouts come with an error message; the others
+// are expected outcomes that scripts test for.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	} else if err == ErrUsage {
+		return 2
+	} else if err == ErrNoBuckets || err == ErrNotExists {
+		return 3
+	} else if err == ErrCondition {
+		return 4
+	} else if errors.Is(err, ErrLocked) {
+		return 5
+	} else if errors.Is(err, syscall.EPIPE) {
+		// The reader went away, e.g. "bolt list ... | head". Exit
+		// quietly with the status of a process killed by SIGPIPE.
+		return 141
+	} else if errors.Is(err, ErrInterrupted) {
+		// Ctrl-C: the status of a process killed by SIGINT.
+		return 130
 	}
+	return 1
 }
 
 // Main represents the main program execution.
@@ -39,6 +110,9 @@ type Main struct {
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+
+	// JSONErrors is set by a leading -json-errors flag.
+	JSONErrors bool
 }
 
 // NewMain returns a new instance of Main connect to the standard input/output.
@@ -52,6 +126,12 @@ func NewMain() *Main {
 
 // Run executes the program.
 func (m *Main) Run(args ...string) error {
+	// Global flags come before the command.
+	for len(args) > 0 && (args[0] == "-json-errors" || args[0] == "--json-errors") {
+		m.JSONErrors = true
+		args = args[1:]
+	}
+
 	// Require a command at the beginning.
 	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
 		fmt.Fprintln(m.Stderr, m.Usage())
@@ -67,10 +147,75 @@ func (m *Main) Run(args ...string) error {
 		return newBucketsCommand(m).Run(args[1:]...)
 	case "list":
 		return newListCommand(m).Run(args[1:]...)
+	case "get":
+		return newGetCommand(m).Run(args[1:]...)
+	case "first":
+		return newFirstCommand(m).Run(args[1:]...)
+	case "last":
+		return newLastCommand(m).Run(args[1:]...)
+	case "tail":
+		return newTailCommand(m).Run(args[1:]...)
+	case "exists":
+		return newExistsCommand(m).Run(args[1:]...)
+	case "find":
+		return newFindCommand(m).Run(args[1:]...)
 	case "delete":
 		return newDeleteCommand(m).Run(args[1:]...)
 	case "insert":
 		return newInsertCommand(m).Run(args[1:]...)
+	case "cas":
+		return newCasCommand(m).Run(args[1:]...)
+	case "replace":
+		return newReplaceCommand(m).Run(args[1:]...)
+	case "move":
+		return newMoveCommand(m).Run(args[1:]...)
+	case "truncate":
+		return newTruncateCommand(m).Run(args[1:]...)
+	case "update":
+		return newUpdateCommand(m).Run(args[1:]...)
+	case "set-many":
+		return newSetManyCommand(m).Run(args[1:]...)
+	case "load":
+		return newLoadCommand(m).Run(args[1:]...)
+	case "import-csv":
+		// Kept as an alias, the flags and arguments are load's.
+		return newLoadCommand(m).Run(append([]string{"-format", loadCSV}, args[1:]...)...)
+	case "batch":
+		return newBatchCommand(m).Run(args[1:]...)
+	case "expire-sweep":
+		return newExpireSweepCommand(m).Run(args[1:]...)
+	case "watch":
+		return newWatchCommand(m).Run(args[1:]...)
+	case "set-sequence":
+		return newSetSequenceCommand(m).Run(args[1:]...)
+	case "create-bucket":
+		return newCreateBucketCommand(m).Run(args[1:]...)
+	case "delete-bucket":
+		return newDeleteBucketCommand(m).Run(args[1:]...)
+	case "shell":
+		return newShellCommand(m).Run(args[1:]...)
+	case "export":
+		return newExportCommand(m).Run(args[1:]...)
+	case "import":
+		return newImportCommand(m).Run(args[1:]...)
+	case "dump":
+		return newDumpCommand(m).Run(args[1:]...)
+	case "diff":
+		return newDiffCommand(m).Run(args[1:]...)
+	case "schema":
+		return newSchemaCommand(m).Run(args[1:]...)
+	case "checksum":
+		return newChecksumCommand(m).Run(args[1:]...)
+	case "summary":
+		return newSummaryCommand(m).Run(args[1:]...)
+	case "salvage":
+		return newSalvageCommand(m).Run(args[1:]...)
+	case "check-lock":
+		return newCheckLockCommand(m).Run(args[1:]...)
+	case "bench":
+		return newBenchCommand(m).Run(args[1:]...)
+	case "completion":
+		return newCompletionCommand(m).Run(args[1:]...)
 	default:
 		return ErrUnknownCommand
 	}
@@ -89,17 +234,413 @@ The commands are:
 
     buckets       list buckets in bolt database
     list          list key-value pairs in bucket
+    get           print the value of a key in bucket
+    first         print the first key-value pair in bucket
+    last          print the last key-value pair in bucket
+    tail          print the last key-value pairs in bucket
+    exists        check whether a bucket or key exists
+    find          find the buckets containing a key
     insert        insert a key-value pair into bucket
+    update        update the value of an existing key in bucket
+    set-many      insert key-value pairs from a JSON object
     delete        delete a key-value pair from bucket
+    cas           set a key only if it has the expected value
+    replace       set a key and delete others in one transaction
+    move          move a key-value pair to another bucket
+    truncate      delete all key-value pairs in bucket
+    batch         apply a script of changes in one transaction
+    import-csv    same as load -format csv
+    load          bulk insert pairs from NDJSON, CSV or TSV
+    expire-sweep  delete keys whose expiry has passed
+    watch         print changes to a bucket as they happen
+    create-bucket create a bucket in bolt database
+    delete-bucket remove a bucket and everything in it
+    set-sequence  set the sequence counter of a bucket
+    dump          print a shell script that recreates the database
+    export        write the whole database as JSON
+    import        recreate buckets and pairs from exported JSON
+    diff          compare the contents of two databases
+    schema        guess what kind of values each bucket holds
+    summary       print an overview of the whole database
+    checksum      print a digest of the contents of the database
+    salvage       copy what can be read from a damaged database
+    check-lock    check whether a writer holds the database
+    shell         explore the database from an interactive prompt
+    bench         measure write and read throughput
+    completion    print a shell completion script
+
+All commands accept "-db PATH" in place of the PATH argument. Commands that
+only read the database also accept "-" as PATH to read it from stdin.
+
+Commands that modify the database accept "-touch" to create PATH if it
+doesn't exist yet instead of failing.
+
+Commands that only read the database open it read-only, which takes a
+shared lock and doesn't block the application that owns it. buckets, list,
+get, dump, diff, export and shell accept "-rw" to open it read-write
+instead, taking the exclusive lock and keeping writers out until they
+finish.
+
+Flags may come before or after the arguments. A "--" argument ends flag
+parsing, so bucket names and keys that start with "-" must follow it:
+
+    bolt list my.db -- -weird-bucket
+
+A BUCKET_NAME may be a path to a nested bucket, such as users/settings. A
+top-level bucket whose name contains "/" takes precedence over the path.
+Nested paths work wherever a bucket is read, written or created, e.g. in
+list, get, insert, set-many, load, move and diff -bucket; missing parents
+are created along with the bucket.
+
+If PATH is omitted and -db isn't given, the BOLT_DB environment variable is
+used instead. An explicit PATH always wins. It counts as given when the
+first argument names an existing file, or when a command gets more
+arguments than it takes after PATH, so even a file that doesn't exist yet
+can be named:
+
+    BOLT_DB=my.db bolt get other.db config k
+    BOLT_DB=my.db bolt insert -touch new.db config k v
+
+Sizes such as "buckets -size" are printed human readable (e.g. "1.5 MiB")
+when stdout is a terminal and as raw byte counts otherwise. Pass
+"-bytes human" or "-bytes raw" to buckets, schema or summary to choose
+explicitly.
+
+All commands accept "-timeout DURATION", e.g. "-timeout 5s", to fail with
+"database is locked" and exit status 5 if another process holds the lock on
+PATH for longer. Without it they wait for the lock indefinitely.
+
+All commands accept "-verbose" to print how long opening the database
+took to stderr; list and dump also print the scan time and row count.
+
+Advanced options accepted by all commands, for users who know they need
+them:
+
+    -initial-mmap-size N  initial size in bytes of the memory map
+    -mmap-flags N         flags passed to mmap, e.g. MAP_POPULATE on Linux
+
+For scripts, "bolt -json-errors command ..." reports any failure on stderr
+as a JSON object such as {"error":"bucket not found","code":1}, where code
+is the exit status.
+
+Ctrl-C during "export -o" or "salvage" removes the partial output file and
+exits with status 130.
 
 Use "bolt [command] -h" for more information about a command.
 `, "\n")
 }
 
+// parseFlags parses args into fs. Unlike fs.Parse it also accepts flags
+// placed after positional arguments, e.g. "PATH BUCKET -interval 1s".
+// After the first positional argument only the names of flags defined on
+// fs are taken as flags, so other arguments starting with "-", such as a
+// value of "-1", stay positional as they are with fs.Parse. Everything
+// following a "--" terminator is treated as positional.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		} else if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if j := strings.IndexByte(name, '='); j >= 0 {
+			name = name[:j]
+		}
+		f := fs.Lookup(name)
+		if f == nil && len(positional) > 0 {
+			positional = append(positional, arg)
+			continue
+		}
+
+		// Pull the flag's value along with it unless it's a boolean
+		// flag or the value is given inline as "-name=value".
+		flags = append(flags, arg)
+		if strings.Contains(arg, "=") {
+			continue
+		}
+		if f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return fs.Parse(append(append(flags, "--"), positional...))
+}
+
+// exclusive returns an error naming two of the given flags if more than
+// one of them was set on fs.
+func exclusive(fs *flag.FlagSet, names ...string) error {
+	var set []string
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = append(set, name)
+			}
+		}
+	})
+	if len(set) > 1 {
+		return fmt.Errorf("flags -%s and -%s cannot be used together", set[0], set[1])
+	}
+	return nil
+}
+
+// isSet reports whether the named flag was set on fs, which tells an
+// explicitly empty value apart from the default.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// isBoolFlag returns true if f can be set without an explicit value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
 type CommonCommand struct {
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+
+	dbPath  string
+	quiet   bool
+	verbose bool
+	touch   bool
+	rw      bool
+	bytes   byteFormat
+	timeout time.Duration
+
+	// maxArgs is the number of positional arguments the command takes
+	// after PATH, or anyArgs if there is no limit. Commands set it after
+	// parsing their flags; it tells an explicit PATH apart from BOLT_DB.
+	maxArgs int
+
+	// Advanced bolt tuning options.
+	initialMmapSize int
+	mmapFlags       int
+}
+
+// addFlags registers the flags shared by all commands on fs. They only
+// affect how the database is opened, so every command honors them.
+func (cmd *CommonCommand) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.dbPath, "db", "", "")
+	fs.BoolVar(&cmd.verbose, "verbose", false, "")
+	fs.DurationVar(&cmd.timeout, "timeout", 0, "")
+	fs.IntVar(&cmd.initialMmapSize, "initial-mmap-size", 0, "")
+	fs.IntVar(&cmd.mmapFlags, "mmap-flags", 0, "")
+}
+
+// addWriteFlags registers the flags shared by commands that modify the
+// database. It is called in addition to addFlags.
+func (cmd *CommonCommand) addWriteFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.touch, "touch", false, "")
+}
+
+// addReadFlags registers the flags shared by commands that only read the
+// database. It is called in addition to addFlags.
+func (cmd *CommonCommand) addReadFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.rw, "rw", false, "")
+}
+
+// addTableFlags registers -quiet for commands that print a table header.
+func (cmd *CommonCommand) addTableFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.quiet, "quiet", false, "")
+}
+
+// addSizeFlags registers -bytes for commands that print sizes.
+func (cmd *CommonCommand) addSizeFlags(fs *flag.FlagSet) {
+	fs.Var(&cmd.bytes, "bytes", "")
+}
+
+// header writes the table header lines to stdout unless -quiet is set.
+func (cmd *CommonCommand) header(lines ...string) {
+	if cmd.quiet {
+		return
+	}
+	for _, line := range lines {
+		fmt.Fprintln(cmd.Stdout, line)
+	}
+}
+
+// anyArgs is the maxArgs of commands taking any number of arguments.
+const anyArgs = -1
+
+// flagPath returns the database path given by the -db flag or, failing
+// that, the BOLT_DB environment variable. A first argument that is "-" or
+// an existing file, or more positional arguments than the command takes
+// after PATH, mean PATH was given explicitly, which overrides BOLT_DB. It
+// returns an empty string if the path is positional.
+func (cmd *CommonCommand) flagPath(fs *flag.FlagSet) string {
+	if cmd.dbPath != "" {
+		return cmd.dbPath
+	}
+
+	env := os.Getenv("BOLT_DB")
+	if env == "" || fs.Arg(0) == "-" || cmd.maxArgs != anyArgs && fs.NArg() > cmd.maxArgs {
+		return ""
+	} else if fi, err := os.Stat(fs.Arg(0)); err == nil && fi.Mode().IsRegular() {
+		return ""
+	}
+	return env
+}
+
+// path returns the database path. The -db flag and BOLT_DB take precedence
+// over the first positional argument.
+func (cmd *CommonCommand) path(fs *flag.FlagSet) string {
+	if path := cmd.flagPath(fs); path != "" {
+		return path
+	}
+	return fs.Arg(0)
+}
+
+// arg returns the i'th positional argument following the database path,
+// or an empty string if it doesn't exist.
+func (cmd *CommonCommand) arg(fs *flag.FlagSet, i int) string {
+	if cmd.flagPath(fs) == "" {
+		i++
+	}
+	return fs.Arg(i)
+}
+
+// options returns the bolt options set by the shared flags.
+func (cmd *CommonCommand) options(readOnly bool) *bolt.Options {
+	return &bolt.Options{
+		ReadOnly:        readOnly,
+		Timeout:         cmd.timeout,
+		InitialMmapSize: cmd.initialMmapSize,
+		MmapFlags:       cmd.mmapFlags,
+	}
+}
+
+// openStdinDB copies a database piped over stdin into a temporary file and
+// opens it read-only. Bolt needs a seekable, mappable file so it can't read
+// stdin directly. The temporary file is unlinked as soon as it is open.
+func (cmd *CommonCommand) openStdinDB(opts ...func(*bolt.Options)) (*bolt.DB, error) {
+	f, err := os.CreateTemp("", "bolttools-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if _, err := io.Copy(f, cmd.Stdin); err != nil {
+		_ = f.Close()
+		return nil, err
+	} else if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	options := cmd.options(true)
+	for _, opt := range opts {
+		opt(options)
+	}
+	return bolt.Open(f.Name(), 0666, options)
+}
+
+// openDB validates path and opens the bolt database at it. Read-only
+// commands should pass readOnly so they only take a shared lock; they may
+// also read the database from stdin by passing "-" as the path. Any opts
+// are applied to the bolt options before opening. A lock that can't be
+// taken within the timeout is reported as ErrLocked with advice.
+func (cmd *CommonCommand) openDB(path string, readOnly bool, opts ...func(*bolt.Options)) (*bolt.DB, error) {
+	if path == "" {
+		return nil, ErrPathRequired
+	} else if path == "-" {
+		if !readOnly {
+			return nil, ErrStdinWrite
+		}
+		return cmd.openStdinDB(opts...)
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		// bolt.Open would create the file; only let it for -touch.
+		if readOnly || !cmd.touch {
+			return nil, ErrFileNotFound
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	options := cmd.options(readOnly)
+	for _, opt := range opts {
+		opt(options)
+	}
+	start := time.Now()
+	db, err := bolt.Open(path, 0666, options)
+	cmd.verbosef("open: %s\n", time.Since(start))
+	if err == bolt.ErrTimeout {
+		// Bolt only says "timeout", which doesn't tell the user that
+		// someone else has the file.
+		err = fmt.Errorf("%w: timed out waiting for the lock on %s. Another process "+
+			"has it open for writing, such as the application that owns it or another "+
+			"bolt command; stop it or try again later. If nothing should be using the "+
+			"file, look for a hung process still holding it", ErrLocked, path)
+	}
+	return db, err
+}
+
+// verbosef writes a -verbose diagnostic line to stderr.
+func (cmd *CommonCommand) verbosef(format string, a ...interface{}) {
+	if cmd.verbose {
+		fmt.Fprintf(cmd.Stderr, format, a...)
+	}
+}
+
+// printTxStats writes the write-related transaction statistics of db to
+// stderr. It is meant to be called after the command's update commits.
+func (cmd *CommonCommand) printTxStats(db *bolt.DB) {
+	stats := db.Stats().TxStats
+	fmt.Fprintf(cmd.Stderr, "PageCount: %d\n", stats.PageCount)
+	fmt.Fprintf(cmd.Stderr, "PageAlloc: %d\n", stats.PageAlloc)
+	fmt.Fprintf(cmd.Stderr, "Split:     %d\n", stats.Split)
+	fmt.Fprintf(cmd.Stderr, "Spill:     %d\n", stats.Spill)
+	fmt.Fprintf(cmd.Stderr, "Write:     %d\n", stats.Write)
+	fmt.Fprintf(cmd.Stderr, "WriteTime: %s\n", stats.WriteTime)
+}
+
+// progressInterval is how often -progress reports on a running scan.
+var progressInterval = time.Second
+
+// startProgress writes the number of keys counted in n and the elapsed time
+// to stderr every progressInterval until the returned function is called.
+// n must only be updated atomically while progress is running.
+func (cmd *CommonCommand) startProgress(n *int64) (stop func()) {
+	start := time.Now()
+	ticker := time.NewTicker(progressInterval)
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				elapsed := time.Since(start).Round(time.Second)
+				fmt.Fprintf(cmd.Stderr, "progress: %d keys, %s elapsed\n", atomic.LoadInt64(n), elapsed)
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		<-exited
+	}
+}
+
+// narg returns the number of positional arguments following the database
+// path.
+func (cmd *CommonCommand) narg(fs *flag.FlagSet) int {
+	if cmd.flagPath(fs) != "" || fs.NArg() == 0 {
+		return fs.NArg()
+	}
+	return fs.NArg() - 1
 }
 
 type BucketsCommand struct {
@@ -120,51 +661,406 @@ func newBucketsCommand(m *Main) *BucketsCommand {
 func (cmd *BucketsCommand) Run(args ...string) error {
 	// Parse flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	cmd.addTableFlags(fs)
+	cmd.addSizeFlags(fs)
 	help := fs.Bool("h", false, "")
-	if err := fs.Parse(args); err != nil {
+	namesOnly := fs.Bool("names-only", false, "")
+	size := fs.Bool("size", false, "")
+	wide := fs.Bool("wide", false, "")
+	var recursive bool
+	fs.BoolVar(&recursive, "r", false, "")
+	fs.BoolVar(&recursive, "recursive", false, "")
+	maxDepth := fs.Int("max-depth", 100, "")
+	parallel := fs.Int("parallel", 1, "")
+	nonEmpty := fs.Bool("non-empty", false, "")
+	format := fs.String("format", outputText, "")
+	if err := parseFlags(fs, args); err != nil {
 		return err
 	} else if *help {
 		fmt.Fprintln(cmd.Stderr, cmd.Usage())
 		return ErrUsage
+	} else if err := checkOutput(*format); err != nil {
+		return err
+	} else if err := exclusive(fs, "names-only", "format"); err != nil {
+		return err
+	} else if err := exclusive(fs, "names-only", "size", "wide"); err != nil {
+		return err
+	} else if err := exclusive(fs, "r", "recursive", "size"); err != nil {
+		return err
 	}
 
-	// Require database path.
-	path := fs.Arg(0)
-	if path == "" {
-		return ErrPathRequired
-	} else if _, err := os.Stat(path); os.IsNotExist(err) {
-		return ErrFileNotFound
-	}
+	cmd.maxArgs = 1
 
 	// Open database.
-	db, err := bolt.Open(path, 0666, nil)
+	db, err := cmd.openDB(cmd.path(fs), !cmd.rw)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = db.Close() }()
 
+	// With a BUCKET_NAME only the buckets nested in it are listed.
+	var infos []boltview.BucketInfo
+	if parent := cmd.arg(fs, 0); parent != "" {
+		infos, err = boltview.NestedBuckets(db, parent, recursive, *maxDepth)
+	} else if recursive {
+		infos, err = boltview.AllBucketsDepth(db, *maxDepth)
+	} else {
+		infos, err = boltview.Buckets(db)
+	}
+	if err != nil {
+		return err
+	}
+	if *nonEmpty {
+		kept := infos[:0]
+		for _, info := range infos {
+			if info.KeyN > 0 {
+				kept = append(kept, info)
+			}
+		}
+		infos = kept
+	}
+
+	if *namesOnly {
+		if len(infos) == 0 {
+			return ErrNoBuckets
+		}
+		for _, info := range infos {
+			fmt.Fprintln(cmd.Stdout, info.Name)
+		}
+		return nil
+	}
+
+	// The name column is 8 wide unless -wide sizes it to the longest name.
+	width := 8
+	if *wide {
+		for _, info := range infos {
+			if len(info.Name) > width {
+				width = len(info.Name)
+			}
+		}
+	}
+
+	var sizes []int64
+	if *size {
+		if sizes, err = bucketSizes(db, infos, *parallel); err != nil {
+			return err
+		}
+	}
+
+	if *format == outputJSON {
+		return cmd.printJSON(infos, sizes)
+	}
+
 	// Write header.
-	fmt.Fprintln(cmd.Stdout, "NAME     ITEMS")
-	fmt.Fprintln(cmd.Stdout, "======== ========")
+	rule := strings.Repeat("=", width)
+	if *size {
+		cmd.header(fmt.Sprintf("%-*s ITEMS    SIZE", width, "NAME"), rule+" ======== ========")
+	} else {
+		cmd.header(fmt.Sprintf("%-*s ITEMS", width, "NAME"), rule+" ========")
+	}
 
-	return db.View(func(tx *bolt.Tx) error {
-		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
-			fmt.Fprintf(cmd.Stdout, "%-8s %-8d\n", string(name), bucket.Stats().KeyN)
-			return nil
-		})
+	for i, info := range infos {
+		if !*size {
+			fmt.Fprintf(cmd.Stdout, "%-*s %-8d\n", width, info.Name, info.KeyN)
+			continue
+		}
+		fmt.Fprintf(cmd.Stdout, "%-*s %-8d %s\n", width, info.Name, info.KeyN, cmd.formatBytes(sizes[i]))
+	}
+	return nil
+}
+
+// printJSON writes the buckets as a JSON array of objects with the name,
+// the item count and, if sizes is set, the size of each bucket.
+func (cmd *BucketsCommand) printJSON(infos []boltview.BucketInfo, sizes []int64) error {
+	type jsonBucket struct {
+		Name         string `json:"name"`
+		NameEncoding string `json:"name_encoding,omitempty"`
+		Items        int    `json:"items"`
+		Size         *int64 `json:"size,omitempty"`
+	}
+	buckets := make([]jsonBucket, len(infos))
+	for i, info := range infos {
+		b := &buckets[i]
+		b.Name, b.NameEncoding = jsonText(info.Name)
+		b.Items = info.KeyN
+		if sizes != nil {
+			b.Size = &sizes[i]
+		}
+	}
+	out, err := json.MarshalIndent(buckets, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.Stdout, string(out))
+	return err
+}
+
+// bucketSizes returns the size of each bucket, scanning up to n buckets at
+// once in separate read transactions.
+func bucketSizes(db *bolt.DB, infos []boltview.BucketInfo, n int) ([]int64, error) {
+	if n < 1 {
+		n = 1
+	}
+	sizes := make([]int64, len(infos))
+	errs := make([]error, len(infos))
+
+	// Each worker writes only the entries for the indexes it receives.
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				sizes[i], errs[i] = boltview.Size(db, infos[i].Name)
+			}
+		}()
+	}
+	for i := range infos {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sizes, nil
+}
+
+// checkDecode returns an error if decode is not a valid -decode mode.
+func checkDecode(decode string) error {
+	if decode != "none" && decode != "gzip" {
+		return fmt.Errorf("unknown decoding %q: must be none or gzip", decode)
+	}
+	return nil
+}
+
+// decodeValue undoes the -decode mode on the value of key. A value that
+// can't be decoded is returned unchanged after a warning on stderr.
+func (cmd *CommonCommand) decodeValue(decode string, key, v []byte) []byte {
+	if decode != "gzip" || v == nil {
+		return v
+	}
+	b, err := gunzip(v)
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "warning: value of %q is not gzip (%s), printing it raw\n", key, err)
+		return v
+	}
+	return b
+}
+
+// gunzip returns the decompressed contents of v.
+func gunzip(v []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(v))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// prettyJSON returns v indented if it is valid JSON, or v unchanged.
+func prettyJSON(v []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, v, "", "  "); err != nil {
+		return v
+	}
+	return buf.Bytes()
+}
+
+// selectJSON returns the field at a dotted path such as "user.name" in the
+// JSON value v; array elements are selected by index, as in "tags.0".
+// Strings are returned unquoted and anything else as JSON. ok is false if
+// v isn't JSON or has no such field.
+func selectJSON(v []byte, path string) (field []byte, ok bool) {
+	if !json.Valid(v) {
+		return nil, false
+	}
+	var x interface{}
+	d := json.NewDecoder(bytes.NewReader(v))
+	d.UseNumber()
+	if err := d.Decode(&x); err != nil {
+		return nil, false
+	}
+	for _, name := range strings.Split(path, ".") {
+		switch t := x.(type) {
+		case map[string]interface{}:
+			if x, ok = t[name]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			x = t[i]
+		default:
+			return nil, false
+		}
+	}
+	if s, ok := x.(string); ok {
+		return []byte(s), true
+	}
+	b, err := json.Marshal(x)
+	return b, err == nil
+}
+
+// truncateValue cuts s to at most max bytes and appends a suffix with the
+// number of bytes omitted. A max of zero or less leaves s unchanged.
+func truncateValue(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	return fmt.Sprintf("%s…(+%d more)", s[:max], len(s)-max)
+}
+
+// truncateWidth cuts s to at most max characters, replacing the last one
+// kept with "…" so the cut is visible. A max of zero or less leaves s
+// unchanged.
+func truncateWidth(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:max-1]) + "…"
+}
+
+// expandEnv replaces ${VAR} and $VAR references in s with the values of
+// environment variables. Undefined variables expand to the empty string,
+// or are an error if strict is set.
+func expandEnv(s string, strict bool) (string, error) {
+	var missing []string
+	s = os.Expand(s, func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
 	})
+	if strict && len(missing) > 0 {
+		return "", fmt.Errorf("undefined environment variable %s", strings.Join(missing, ", "))
+	}
+	return s, nil
+}
+
+// byteFormat is the value of the -bytes flag. The zero value picks human
+// readable sizes when stdout is a terminal and raw byte counts otherwise.
+type byteFormat string
+
+func (f *byteFormat) String() string { return string(*f) }
+
+func (f *byteFormat) Set(s string) error {
+	if s != "human" && s != "raw" {
+		return errors.New("must be human or raw")
+	}
+	*f = byteFormat(s)
+	return nil
+}
+
+// formatBytes formats a size in bytes as selected by -bytes.
+func (cmd *CommonCommand) formatBytes(n int64) string {
+	if cmd.bytes == "human" || (cmd.bytes == "" && isTerminal(cmd.Stdout)) {
+		return humanizeBytes(n)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// humanizeBytes formats n as a human readable size, e.g. "1.5 KiB".
+func humanizeBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
 
 func (cmd *BucketsCommand) Usage() string {
 	return strings.TrimLeft(`
-usage: bolt buckets PATH
+usage: bolt buckets [-names-only] [-size] [-parallel N] [-quiet] [-wide] [-r]
+                    [-max-depth N] [-non-empty] [-format FORMAT] [-rw]
+                    PATH [BUCKET_NAME]
+
+Buckets prints a table of buckets in bolt database. With BUCKET_NAME, such
+as users or users/settings, it lists the buckets nested in that bucket
+instead of the top-level ones.
+
+Additional options include:
 
-Buckets prints a table of buckets in bolt database
+	-quiet
+		Omit the header lines and print only the data rows.
+	-names-only
+		Print one bucket name per line without the header or item
+		counts. Exits with status 3 if the database has no buckets.
+	-size
+		Add a SIZE column with the total length of the keys and values
+		in each bucket. This scans every bucket, so it can be slow on
+		large databases. See -bytes for the format.
+	-parallel N
+		With -size, scan up to N buckets at once, each in its own
+		read transaction (default 1). This helps on databases with
+		many large buckets and multiple CPUs.
+	-wide
+		Size the NAME column to the longest bucket name instead of
+		the fixed 8 characters.
+	-r, -recursive
+		Also list nested buckets, named by their path such as
+		"parent/child". ITEMS counts the keys in each bucket and all
+		of its sub-buckets.
+	-max-depth N
+		With -r, fail instead of descending into buckets nested more
+		than N levels deep (default 100). Zero means no limit.
+	-non-empty
+		Leave out buckets with no items. Empty buckets are listed by
+		default.
+	-format FORMAT
+		Print a table (text, the default) or a JSON array of objects
+		with the name, items and, with -size, the size in bytes of
+		each bucket, e.g. for jq. Names that aren't valid UTF-8 are
+		base64 encoded and have "name_encoding": "base64".
 `, "\n")
 }
 
 type ListCommand struct {
 	CommonCommand
+
+	// Options shared by every bucket listed, set by Run.
+	opts        boltview.ScanOptions
+	maxKeyWidth int
+	maxValWidth int
+	keyType     string
+	valueType   string
+	maxValue    int
+	valuesOnly  bool
+	sortOrder   string
+	stripPrefix string
+	strict      bool
+	decode      string
+	pretty      bool
+	selectField string
+	skipMissing bool
+	format      string
+
+	// n counts the keys listed so far for -progress.
+	n int64
 }
 
 func newListCommand(m *Main) *ListCommand {
@@ -181,60 +1077,608 @@ func newListCommand(m *Main) *ListCommand {
 func (cmd *ListCommand) Run(args ...string) error {
 	// Parse flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	cmd.addTableFlags(fs)
 	help := fs.Bool("h", false, "")
-	if err := fs.Parse(args); err != nil {
+	after := fs.String("after", "", "")
+	prefix := fs.String("prefix", "", "")
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	excludePrefix := fs.String("exclude-prefix", "", "")
+	fs.IntVar(&cmd.opts.Limit, "limit", 0, "")
+	fs.IntVar(&cmd.opts.Offset, "offset", 0, "")
+	fs.BoolVar(&cmd.opts.Reverse, "reverse", false, "")
+	wide := fs.Bool("wide", false, "")
+	fs.IntVar(&cmd.maxKeyWidth, "max-key-width", 40, "")
+	fs.IntVar(&cmd.maxValWidth, "max-value-width", 0, "")
+	noTruncate := fs.Bool("no-truncate", false, "")
+	fs.BoolVar(&cmd.opts.NoBuckets, "no-buckets", false, "")
+	fs.BoolVar(&cmd.valuesOnly, "values-only", false, "")
+	fs.StringVar(&cmd.sortOrder, "sort", "byte", "")
+	fs.StringVar(&cmd.keyType, "key-type", typeString, "")
+	fs.StringVar(&cmd.valueType, "value-type", typeString, "")
+	fs.Var(encodingFlag{&cmd.keyType}, "key-encoding", "")
+	fs.Var(encodingFlag{&cmd.valueType}, "value-encoding", "")
+	fs.IntVar(&cmd.maxValue, "max-value", 0, "")
+	progress := fs.Bool("progress", false, "")
+	fs.StringVar(&cmd.stripPrefix, "strip-prefix", "", "")
+	fs.StringVar(&cmd.decode, "decode", "none", "")
+	fs.BoolVar(&cmd.pretty, "pretty", false, "")
+	filter := fs.String("filter", "", "")
+	fs.StringVar(&cmd.format, "format", outputText, "")
+	fs.Var(formatFlag{&cmd.format, outputJSON}, "json", "")
+	fs.Var(formatFlag{&cmd.format, outputCSV}, "csv", "")
+	fs.StringVar(&cmd.selectField, "select", "", "")
+	fs.BoolVar(&cmd.skipMissing, "skip-missing", false, "")
+	formatKey := fs.String("format-key", "", "")
+	formatValue := fs.String("format-value", "", "")
+	since := fs.String("json-since", "", "")
+	until := fs.String("json-until", "", "")
+	tsField := fs.String("ts-field", "ts", "")
+	fs.BoolVar(&cmd.strict, "strict", false, "")
+	if err := parseFlags(fs, args); err != nil {
 		return err
 	} else if *help {
 		fmt.Fprintln(cmd.Stderr, cmd.Usage())
 		return ErrUsage
+	} else if err := exclusive(fs, "format-key", "key-type", "key-encoding"); err != nil {
+		return err
+	} else if err := exclusive(fs, "format-value", "value-type", "pretty", "value-encoding"); err != nil {
+		return err
+	} else if err := exclusive(fs, "format", "json", "csv"); err != nil {
+		return err
 	}
 
-	// Require database path.
-	path := fs.Arg(0)
-	if path == "" {
-		return ErrPathRequired
-	} else if _, err := os.Stat(path); os.IsNotExist(err) {
-		return ErrFileNotFound
+	cmd.maxArgs = anyArgs
+
+	// -format-key and -format-value pick the key and value display
+	// independently, in place of -key-type, -value-type and -pretty.
+	var err error
+	if *formatKey != "" {
+		if cmd.keyType, _, err = parseFormat(*formatKey, false); err != nil {
+			return err
+		}
+	}
+	if *formatValue != "" {
+		if cmd.valueType, cmd.pretty, err = parseFormat(*formatValue, true); err != nil {
+			return err
+		}
+	}
+
+	if err := checkType(cmd.keyType); err != nil {
+		return err
+	} else if err := checkType(cmd.valueType); err != nil {
+		return err
+	} else if err := exclusive(fs, "values-only", "wide"); err != nil {
+		return err
+	} else if err := exclusive(fs, "no-truncate", "wide", "max-key-width"); err != nil {
+		return err
+	} else if err := exclusive(fs, "no-truncate", "max-value-width"); err != nil {
+		return err
+	} else if cmd.maxKeyWidth < 0 || cmd.maxValWidth < 0 {
+		return errors.New("-max-key-width and -max-value-width must not be negative")
+	} else if cmd.format != outputText && cmd.format != outputJSON && cmd.format != outputCSV {
+		return fmt.Errorf("unknown format %q: must be text, json or csv", cmd.format)
+	} else if cmd.format != outputText && (cmd.valuesOnly || *wide) {
+		return fmt.Errorf("-format %s cannot be used with -values-only or -wide", cmd.format)
+	} else if err := checkDecode(cmd.decode); err != nil {
+		return err
+	} else if cmd.opts.Limit < 0 || cmd.opts.Offset < 0 {
+		return errors.New("-limit and -offset must not be negative")
+	} else if cmd.sortOrder != "byte" && cmd.sortOrder != "numeric" {
+		return fmt.Errorf("unknown sort order %q: must be byte or numeric", cmd.sortOrder)
+	} else if cmd.sortOrder == "numeric" && *after != "" {
+		return errors.New("-after cannot be used with -sort numeric")
+	} else if cmd.sortOrder == "numeric" && (*from != "" || *to != "") {
+		return errors.New("-from and -to cannot be used with -sort numeric")
+	}
+
+	// -wide predates -max-key-width and keeps whole keys.
+	if *wide {
+		cmd.maxKeyWidth = 0
+	} else if *noTruncate {
+		cmd.maxKeyWidth, cmd.maxValWidth = 0, 0
+	}
+
+	// Nested buckets have no value to print.
+	if cmd.valuesOnly || cmd.format == outputCSV {
+		cmd.opts.NoBuckets = true
+	}
+
+	var bucketNames []string
+	for i := 0; i < cmd.narg(fs); i++ {
+		bucketNames = append(bucketNames, cmd.arg(fs, i))
+	}
+	if len(bucketNames) == 0 || bucketNames[0] == "" {
+		return ErrBucketRequired
+	} else if len(bucketNames) > 1 && cmd.format == outputCSV {
+		return errors.New("-format csv lists a single bucket")
+	}
+
+	if *after != "" {
+		if cmd.opts.After, err = encodeType(cmd.keyType, *after); err != nil {
+			return fmt.Errorf("invalid %s key: %s", cmd.keyType, err)
+		}
+	}
+	if *prefix != "" {
+		if cmd.opts.Prefix, err = encodeType(cmd.keyType, *prefix); err != nil {
+			return fmt.Errorf("invalid %s prefix: %s", cmd.keyType, err)
+		}
+	}
+	if *from != "" {
+		if cmd.opts.From, err = encodeType(cmd.keyType, *from); err != nil {
+			return fmt.Errorf("invalid %s key: %s", cmd.keyType, err)
+		}
+	}
+	if *to != "" {
+		if cmd.opts.To, err = encodeType(cmd.keyType, *to); err != nil {
+			return fmt.Errorf("invalid %s key: %s", cmd.keyType, err)
+		}
+	}
+	if *excludePrefix != "" {
+		if cmd.opts.ExcludePrefix, err = encodeType(cmd.keyType, *excludePrefix); err != nil {
+			return fmt.Errorf("invalid %s prefix: %s", cmd.keyType, err)
+		}
+	}
+
+	if *since != "" || *until != "" {
+		if cmd.opts.Filter, err = cmd.timeFilter(*tsField, *since, *until); err != nil {
+			return err
+		}
+	}
+	if *filter != "" {
+		match, err := parseFilter(*filter)
+		if err != nil {
+			return fmt.Errorf("invalid -filter: %s", err)
+		}
+		next := cmd.opts.Filter
+		cmd.opts.Filter = func(k, v []byte) bool {
+			return (next == nil || next(k, v)) && match(k, v)
+		}
+	}
+	if cmd.selectField != "" && cmd.skipMissing {
+		cmd.opts.Filter = cmd.selectFilter(cmd.opts.Filter)
 	}
 
 	// Open database.
-	db, err := bolt.Open(path, 0666, nil)
+	db, err := cmd.openDB(cmd.path(fs), !cmd.rw)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = db.Close() }()
 
-	bucketName := fs.Arg(1)
-	if bucketName == "" {
-		return ErrBucketRequired
+	if *progress {
+		defer cmd.startProgress(&cmd.n)()
+	}
+
+	// Buffer the rows since a write per row is slow on large buckets. The
+	// buffer is flushed even if listing fails part way through.
+	start := time.Now()
+	w := bufio.NewWriter(cmd.Stdout)
+	cmd.Stdout = w
+	err = cmd.listAll(db, bucketNames)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	cmd.verbosef("scan: %s, %d rows\n", time.Since(start), atomic.LoadInt64(&cmd.n))
+	return err
+}
+
+// listAll lists each of the buckets in turn.
+func (cmd *ListCommand) listAll(db *bolt.DB, bucketNames []string) error {
+	// A single bucket is listed as a plain table.
+	if len(bucketNames) == 1 {
+		return cmd.list(db, bucketNames[0])
+	}
+
+	// Otherwise each bucket gets its own section. Missing buckets are
+	// skipped with a warning unless -strict is set.
+	sections := 0
+	for _, bucketName := range bucketNames {
+		if err := db.View(func(tx *bolt.Tx) error {
+			_, err := boltview.LookupBucket(tx, bucketName)
+			return err
+		}); err == ErrBucketNotFound && !cmd.strict {
+			fmt.Fprintf(cmd.Stderr, "warning: bucket %q not found\n", bucketName)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		// JSON lines name their bucket, so they need no sections.
+		if cmd.format != outputJSON {
+			if sections > 0 {
+				fmt.Fprintln(cmd.Stdout)
+			}
+			sections++
+			fmt.Fprintf(cmd.Stdout, "# %s\n", bucketName)
+		}
+		if err := cmd.list(db, bucketName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list prints the table of key-value pairs in a single bucket.
+func (cmd *ListCommand) list(db *bolt.DB, bucketName string) error {
+	if cmd.format == outputJSON {
+		return cmd.listJSON(db, bucketName)
+	} else if cmd.format == outputCSV {
+		return cmd.listCSV(db, bucketName)
+	} else if cmd.valuesOnly {
+		return cmd.scan(db, bucketName, func(k, v []byte) error {
+			atomic.AddInt64(&cmd.n, 1)
+			_, err := fmt.Fprintln(cmd.Stdout, cmd.displayValue(k, v))
+			return err
+		})
+	}
+
+	// A first pass measures the longest key so the KEY column fits it, up
+	// to -max-key-width. Longer keys are cut with a visible "…".
+	width := 12
+	if err := cmd.scan(db, bucketName, func(k, _ []byte) error {
+		key, err := cmd.displayKey(k)
+		if n := utf8.RuneCountInString(key); n > width {
+			width = n
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	if cmd.maxKeyWidth > 0 && width > cmd.maxKeyWidth {
+		width = cmd.maxKeyWidth
 	}
 
 	// Write header.
-	fmt.Fprintln(cmd.Stdout, "KEY          VALUE")
-	fmt.Fprintln(cmd.Stdout, "============ ============")
+	cmd.header(fmt.Sprintf("%-*s VALUE", width, "KEY"), strings.Repeat("=", width)+" ============")
 
-	return db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return ErrBucketNotFound
+	return cmd.scan(db, bucketName, func(k, v []byte) error {
+		atomic.AddInt64(&cmd.n, 1)
+		key, err := cmd.displayKey(k)
+		if err != nil {
+			return err
 		}
+		key = truncateWidth(key, width)
+		// Nested buckets have no value of their own.
+		value := "[bucket]"
+		if v != nil {
+			value = truncateWidth(cmd.displayValue(k, v), cmd.maxValWidth)
+		}
+		// Stop at the first failed write, e.g. when piped into head.
+		_, err = fmt.Fprintf(cmd.Stdout, "%-*s %-12s\n", width, key, value)
+		return err
+	})
+}
 
-		cursor := bucket.Cursor()
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			if len(k) > 12 {
-				k = k[0:12]
+// listJSON prints each pair in the bucket as a JSON object on its own
+// line, with the whole key and value as they would be displayed.
+func (cmd *ListCommand) listJSON(db *bolt.DB, bucketName string) error {
+	enc := json.NewEncoder(cmd.Stdout)
+	enc.SetEscapeHTML(false)
+	return cmd.scan(db, bucketName, func(k, v []byte) error {
+		atomic.AddInt64(&cmd.n, 1)
+		key, err := cmd.displayKey(k)
+		if err != nil {
+			return err
+		}
+		p := jsonPair{Bucket: bucketName, Nested: v == nil}
+		p.Key, p.KeyEncoding = jsonText(key)
+		if v != nil {
+			var value string
+			value, p.ValueEncoding = jsonText(cmd.displayValue(k, v))
+			p.Value = &value
+		}
+		return enc.Encode(p)
+	})
+}
+
+// listCSV prints the pairs in the bucket as "key,value" records, under a
+// header record unless -quiet is set, as read by "bolt load -format csv".
+func (cmd *ListCommand) listCSV(db *bolt.DB, bucketName string) error {
+	w := csv.NewWriter(cmd.Stdout)
+	if !cmd.quiet {
+		_ = w.Write([]string{"key", "value"})
+	}
+	err := cmd.scan(db, bucketName, func(k, v []byte) error {
+		atomic.AddInt64(&cmd.n, 1)
+		key, err := cmd.displayKey(k)
+		if err != nil {
+			return err
+		}
+		return w.Write([]string{key, cmd.displayValue(k, v)})
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	return err
+}
+
+// displayValue decodes v, selects the -select field from it and formats
+// it for display. A value without the field is shown empty.
+func (cmd *ListCommand) displayValue(k, v []byte) string {
+	v = cmd.decodeValue(cmd.decode, k, v)
+	if cmd.selectField != "" {
+		v, _ = selectJSON(v, cmd.selectField)
+	}
+	if cmd.pretty {
+		v = prettyJSON(v)
+	}
+	return truncateValue(decodeType(cmd.valueType, v), cmd.maxValue)
+}
+
+// selectFilter wraps the scan filter next so that it also skips values
+// without the -select field, including nested buckets. Values are
+// decompressed first with -decode gzip, without repeating its warnings.
+func (cmd *ListCommand) selectFilter(next func(k, v []byte) bool) func(k, v []byte) bool {
+	return func(k, v []byte) bool {
+		if v == nil || (next != nil && !next(k, v)) {
+			return false
+		}
+		if cmd.decode == "gzip" {
+			if b, err := gunzip(v); err == nil {
+				v = b
 			}
-			fmt.Fprintf(cmd.Stdout, "%-12s %-12s\n", string(k), string(v))
 		}
+		_, ok := selectJSON(v, cmd.selectField)
+		return ok
+	}
+}
+
+// timeFilter returns a scan filter keeping JSON object values whose field
+// holds an RFC3339 timestamp in [since, until). Either bound may be empty.
+// Other values are skipped, with a warning when -verbose is set.
+func (cmd *ListCommand) timeFilter(field, since, until string) (func(k, v []byte) bool, error) {
+	var from, to time.Time
+	var err error
+	if since != "" {
+		if from, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("invalid -json-since: %s", err)
+		}
+	}
+	if until != "" {
+		if to, err = time.Parse(time.RFC3339, until); err != nil {
+			return nil, fmt.Errorf("invalid -json-until: %s", err)
+		}
+	}
+
+	return func(k, v []byte) bool {
+		if v == nil {
+			return false
+		}
+		var obj map[string]json.RawMessage
+		var ts time.Time
+		var s string
+		if err := json.Unmarshal(v, &obj); err != nil {
+			cmd.verbosef("warning: skipping %q: value is not a JSON object\n", k)
+			return false
+		} else if err := json.Unmarshal(obj[field], &s); err != nil {
+			cmd.verbosef("warning: skipping %q: no string field %q\n", k, field)
+			return false
+		} else if ts, err = time.Parse(time.RFC3339, s); err != nil {
+			cmd.verbosef("warning: skipping %q: %s\n", k, err)
+			return false
+		}
+		return (from.IsZero() || !ts.Before(from)) && (to.IsZero() || ts.Before(to))
+	}, nil
+}
+
+// displayKey decodes k for display and removes -strip-prefix from it. With
+// -strict a key without the prefix is an error.
+func (cmd *ListCommand) displayKey(k []byte) (string, error) {
+	key := decodeType(cmd.keyType, k)
+	if cmd.stripPrefix == "" {
+		return key, nil
+	} else if !strings.HasPrefix(key, cmd.stripPrefix) && cmd.strict {
+		return "", fmt.Errorf("key %q does not start with %q", key, cmd.stripPrefix)
+	}
+	return strings.TrimPrefix(key, cmd.stripPrefix), nil
+}
+
+// scan calls fn for the pairs selected by the list options. Pairs are
+// visited in byte order straight from the cursor, or with -sort numeric
+// collected first and sorted by the numeric value of their keys.
+func (cmd *ListCommand) scan(db *bolt.DB, bucketName string, fn func(k, v []byte) error) error {
+	if cmd.sortOrder != "numeric" {
+		return boltview.Scan(db, bucketName, cmd.opts, fn)
+	}
+
+	opts := cmd.opts
+	opts.Limit, opts.Offset, opts.Reverse = 0, 0, false
+	var pairs []boltview.Pair
+	if err := boltview.Scan(db, bucketName, opts, func(k, v []byte) error {
+		// Copy the pair since it must outlive the transaction; a nil
+		// value marks a nested bucket and has to stay nil.
+		p := boltview.Pair{Key: append([]byte(nil), k...)}
+		if v != nil {
+			p.Value = append([]byte{}, v...)
+		}
+		pairs = append(pairs, p)
 		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Keys that aren't numbers sort after all numeric keys, in byte order.
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, aErr := strconv.ParseFloat(decodeType(cmd.keyType, pairs[i].Key), 64)
+		b, bErr := strconv.ParseFloat(decodeType(cmd.keyType, pairs[j].Key), 64)
+		if aErr != nil || bErr != nil {
+			return aErr == nil && bErr != nil
+		}
+		return a < b
 	})
+	if cmd.opts.Reverse {
+		for i, j := 0, len(pairs)-1; i < j; i, j = i+1, j-1 {
+			pairs[i], pairs[j] = pairs[j], pairs[i]
+		}
+	}
+	if cmd.opts.Offset >= len(pairs) {
+		pairs = nil
+	} else {
+		pairs = pairs[cmd.opts.Offset:]
+	}
+	if cmd.opts.Limit > 0 && len(pairs) > cmd.opts.Limit {
+		pairs = pairs[:cmd.opts.Limit]
+	}
+	for _, p := range pairs {
+		if err := fn(p.Key, p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (cmd *ListCommand) Usage() string {
 	return strings.TrimLeft(`
-usage: bolt list PATH BUCKET_NAME
+usage: bolt list [-quiet] [-rw] [-after KEY] [-limit N] [-offset N] [-reverse]
+                 [-from KEY] [-to KEY] [-max-value N] [-max-key-width N]
+                 [-max-value-width N] [-no-truncate] [-wide]
+                 [-prefix PREFIX] [-exclude-prefix PREFIX]
+                 [-strip-prefix PREFIX] [-no-buckets] [-values-only]
+                 [-sort ORDER] [-progress] [-strict] [-decode gzip] [-pretty]
+                 [-json-since TIME] [-json-until TIME] [-ts-field NAME]
+                 [-key-type TYPE] [-value-type TYPE]
+                 [-key-encoding ENCODING] [-value-encoding ENCODING]
+                 [-format-key FORMAT] [-format-value FORMAT]
+                 [-select FIELD [-skip-missing]] [-filter EXPR]
+                 [-format FORMAT | -json | -csv]
+                 PATH BUCKET_NAME [BUCKET_NAME...]
+
+List prints a table of key-value pairs in that bucket. When several
+buckets are given, each table is printed under a "# BUCKET_NAME" line;
+-after, -offset and -limit apply to each bucket separately. Nested buckets are
+shown with a "[bucket]" value.
 
-List prints a table of key-value pairs in that bucket
+Additional options include:
+
+	-quiet
+		Omit the header lines and print only the data rows.
+	-max-key-width N
+		The KEY column is as wide as the longest key listed, scanning
+		the selected keys twice to measure it, but at most N
+		characters (default 40). Longer keys are cut and end in "…".
+		0 means no limit.
+	-max-value-width N
+		Cut values longer than N characters in the table, ending them
+		in "…". The default of 0 prints whole values.
+	-no-truncate
+		Print whole keys and values, the same as -max-key-width 0
+		-max-value-width 0.
+	-wide
+		Print whole keys, the same as -max-key-width 0.
+	-after KEY
+		Start listing at the first key after KEY. To page through a
+		bucket, pass the last key of the previous page.
+	-limit N
+		Print at most N pairs.
+	-offset N
+		Skip the first N pairs that would be printed, e.g. "-offset 100
+		-limit 50" for the third page of 50. The skipped pairs are
+		still read, so -after is much faster for deep pages of a large
+		bucket.
+	-reverse
+		List keys in descending order, starting from the last one,
+		e.g. the newest entries of a bucket with time-ordered keys.
+		-after KEY then continues with the keys before KEY, so paging
+		works the same way backwards. The other options select the
+		same keys as without -reverse.
+	-from KEY, -to KEY
+		Print only keys from KEY, inclusive, up to but not including
+		the -to KEY, in byte order. Either may be given alone. The
+		cursor seeks to -from and stops at -to, so only the keys in
+		the range are read, e.g. a day of time-ordered keys with
+		-from 2024-01-01 -to 2024-01-02. They combine with -prefix
+		and -after.
+	-no-buckets
+		Omit nested buckets and list only keys with values.
+	-values-only
+		Print only the values, one per line in key order, without a
+		header. Nested buckets are skipped.
+	-sort ORDER
+		List keys in byte order (the default), which streams straight
+		from the database, or in numeric order so that "2" comes
+		before "10". Keys that aren't numbers follow the numeric ones.
+		Numeric order has to read the whole bucket into memory
+		before printing, and cannot be combined with -after.
+	-max-value N
+		Print at most N bytes of each displayed value, followed by a
+		"…(+M more)" suffix giving the number of bytes left out.
+	-decode gzip
+		Gunzip each value before printing it. Values that aren't
+		gzip compressed are printed as stored, with a warning on
+		stderr.
+	-pretty
+		Indent values that are valid JSON. Other values are printed
+		as stored.
+	-select FIELD
+		Show only FIELD of each JSON value instead of the whole
+		value. FIELD is a dotted path such as user.name; array
+		elements are selected by index, as in tags.0. Text fields
+		are printed without quotes. Values that aren't JSON or lack
+		the field are shown empty.
+	-skip-missing
+		With -select, skip values that aren't JSON or lack FIELD,
+		and nested buckets, instead of showing them empty.
+	-format FORMAT
+		Print a table (text, the default), CSV (csv) or one JSON
+		object per pair (json), e.g. for jq:
+		{"bucket":"b","key":"k","value":"v"}. Keys and values are
+		never truncated unless -max-value is set, and other options
+		such as -value-type still apply. Text that isn't valid UTF-8
+		is base64 encoded and marked with "key_encoding" or
+		"value_encoding": "base64". A nested bucket has a null value
+		and "nested": true. The csv format prints "key,value"
+		records of a single bucket under a "key,value" header, which
+		-quiet leaves out, for "bolt load -format csv -header".
+		Nested buckets are skipped.
+	-json, -csv
+		Short for -format json and -format csv.
+	-filter EXPR
+		List only the pairs for which EXPR is true, e.g.
+		'len(value) > 100' or 'key startswith "user:"'. EXPR may use
+		key and value as strings, "quoted" strings, whole numbers,
+		len(...), the comparisons ==, !=, <, <=, >, >=, startswith
+		and contains, and !, && and || with parentheses. Keys and
+		values are compared as stored, before any decoding, and a
+		nested bucket has an empty value. EXPR is checked before the
+		scan starts.
+	-json-since TIME, -json-until TIME
+		List only values that are JSON objects with an RFC3339
+		timestamp, such as 2024-01-02T15:04:05Z, in the -ts-field
+		field that is at or after -json-since and before -json-until.
+		Other values are skipped; -verbose prints why.
+	-ts-field NAME
+		The timestamp field used by -json-since and -json-until
+		(default ts).
+	-strip-prefix PREFIX
+		Remove PREFIX from the start of each printed key. This only
+		changes the display; use -prefix to select the keys.
+	-strict
+		Fail on a missing bucket instead of printing a warning and
+		continuing with the remaining buckets, and on a key that
+		doesn't start with -strip-prefix instead of printing it whole.
+	-key-type TYPE, -value-type TYPE
+		Decode keys or values as TYPE for display: string (the
+		default), hex, base64, uint32be or uint64be. Values of the wrong
+		length for an integer type are shown as hex. The -after,
+		-prefix, -exclude-prefix, -from and -to keys are given in
+		the key type too, so binary keys can be selected, e.g.
+		-key-type hex -prefix 00ff.
+	-key-encoding ENCODING, -value-encoding ENCODING
+		Use raw (string), hex or base64 for keys or values, the same
+		as the corresponding -key-type or -value-type.
+	-format-key FORMAT, -format-value FORMAT
+		Choose how keys and values are shown, independently of each
+		other: raw, hex, base64, uint32be, uint64be or, for values
+		only, json-pretty. For example -format-key uint64be
+		-format-value json-pretty. These replace -key-type,
+		-value-type and -pretty and can't be combined with them.
+	-progress
+		Report the number of keys listed and the elapsed time on
+		stderr every second.
 `, "\n")
 }
 
@@ -256,56 +1700,237 @@ func newInsertCommand(m *Main) *InsertCommand {
 func (cmd *InsertCommand) Run(args ...string) error {
 	// Parse flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
 	help := fs.Bool("h", false, "")
-	if err := fs.Parse(args); err != nil {
+	noOverwrite := fs.Bool("no-overwrite", false, "")
+	inFile := fs.String("in", "", "")
+	keyType := fs.String("key-type", typeString, "")
+	valueType := fs.String("value-type", typeString, "")
+	noSync := fs.Bool("no-sync", false, "")
+	stats := fs.Bool("stats", false, "")
+	seq := fs.Bool("seq", false, "")
+	fs.Var(encodingFlag{keyType, valueType}, "input-encoding", "")
+	fs.Var(encodingFlag{keyType}, "key-encoding", "")
+	fs.Var(encodingFlag{valueType}, "value-encoding", "")
+	ifAbsent := fs.Bool("if-absent", false, "")
+	ifPresent := fs.Bool("if-present", false, "")
+	expand := fs.Bool("expand", false, "")
+	strictEnv := fs.Bool("strict-env", false, "")
+	expire := fs.Duration("expire", 0, "")
+	createBucket := fs.Bool("create-bucket", false, "")
+	if err := parseFlags(fs, args); err != nil {
 		return err
 	} else if *help {
 		fmt.Fprintln(cmd.Stderr, cmd.Usage())
 		return ErrUsage
+	} else if err := exclusive(fs, "input-encoding", "key-type", "key-encoding"); err != nil {
+		return err
+	} else if err := exclusive(fs, "input-encoding", "value-type", "value-encoding"); err != nil {
+		return err
+	} else if err := exclusive(fs, "seq", "key-type", "key-encoding"); err != nil {
+		return err
+	} else if err := exclusive(fs, "if-absent", "if-present", "no-overwrite", "seq"); err != nil {
+		return err
+	} else if err := exclusive(fs, "expire", "if-present", "seq"); err != nil {
+		return err
+	} else if err := exclusive(fs, "create-bucket", "if-present"); err != nil {
+		return err
+	} else if *expire < 0 {
+		return errors.New("-expire must not be negative")
+	} else if err := checkType(*keyType); err != nil {
+		return err
+	} else if err := checkType(*valueType); err != nil {
+		return err
 	}
 
-	// Require database path.
-	path := fs.Arg(0)
-	if path == "" {
-		return ErrPathRequired
-	} else if _, err := os.Stat(path); os.IsNotExist(err) {
-		return ErrFileNotFound
+	// With -seq the key is generated, so VALUE directly follows the bucket.
+	valueArg := 2
+	if *seq {
+		valueArg = 1
 	}
-
-	// Open database.
-	db, err := bolt.Open(path, 0666, nil)
-	if err != nil {
-		return err
+	cmd.maxArgs = valueArg + 1
+	if *inFile != "" {
+		cmd.maxArgs = valueArg
 	}
-	defer func() { _ = db.Close() }()
 
-	bucketName := fs.Arg(1)
+	bucketName := cmd.arg(fs, 0)
 	if bucketName == "" {
 		return ErrBucketRequired
 	}
-	key := fs.Arg(2)
-	if key == "" {
-		return ErrKeyRequired
+	var key []byte
+	if !*seq {
+		if key = []byte(cmd.arg(fs, 1)); len(key) == 0 {
+			return ErrKeyRequired
+		}
 	}
-	value := fs.Arg(3)
-	if value == "" {
+
+	// Read the value from a file or from the arguments.
+	var value []byte
+	var err error
+	valueText := cmd.arg(fs, valueArg)
+	if *expand || *strictEnv {
+		if valueText, err = expandEnv(valueText, *strictEnv); err != nil {
+			return err
+		}
+	}
+	if *inFile != "" {
+		if cmd.arg(fs, valueArg) != "" {
+			return ErrValueConflict
+		}
+		if value, err = os.ReadFile(*inFile); err != nil {
+			return err
+		}
+	} else if *valueType != typeString {
+		// An empty encoded value is valid, so only require the argument.
+		if cmd.narg(fs) <= valueArg {
+			return ErrValueRequired
+		}
+		if value, err = encodeType(*valueType, valueText); err != nil {
+			return fmt.Errorf("invalid %s value: %s", *valueType, err)
+		}
+	} else if cmd.arg(fs, valueArg) == "" {
 		return ErrValueRequired
+	} else {
+		// Check the argument itself: it may expand to an empty value.
+		value = []byte(valueText)
 	}
 
-	return db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return ErrBucketNotFound
+	// Encode the key as the requested type.
+	if !*seq {
+		if key, err = encodeKey(*keyType, string(key)); err != nil {
+			return err
 		}
-		return bucket.Put([]byte(key), []byte(value))
-	})
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	db.NoSync = *noSync
+
+	if *ifPresent {
+		if err := boltview.Update(db, bucketName, key, value); err == ErrKeyNotFound {
+			return ErrCondition
+		} else if err != nil {
+			return err
+		}
+	} else {
+		// -create-bucket creates the bucket in the same transaction as
+		// the write, so a failed insert leaves no empty bucket behind.
+		var id uint64
+		if err := db.Update(func(tx *bolt.Tx) error {
+			if *createBucket {
+				if _, err := boltview.CreateBucketIfNotExists(tx, bucketName); err != nil {
+					return err
+				}
+			}
+			if *seq {
+				var err error
+				id, err = boltview.InsertSeqTx(tx, bucketName, value)
+				return err
+			}
+			return cmd.insert(tx, bucketName, key, value, !*noOverwrite && !*ifAbsent, *expire)
+		}); err == ErrKeyExists && *ifAbsent {
+			return ErrCondition
+		} else if err != nil {
+			return err
+		}
+		if *seq {
+			fmt.Fprintln(cmd.Stdout, id)
+		}
+	}
+	if *stats {
+		cmd.printTxStats(db)
+	}
+	return nil
+}
+
+// insert stores the pair, and with a non-zero expire also records when it
+// expires for "bolt expire-sweep".
+func (cmd *InsertCommand) insert(tx *bolt.Tx, bucketName string, key, value []byte, overwrite bool, expire time.Duration) error {
+	if expire == 0 {
+		return boltview.InsertTx(tx, bucketName, key, value, overwrite)
+	}
+	return boltview.InsertExpiringTx(tx, bucketName, key, value, overwrite, time.Now().Add(expire))
 }
 
 func (cmd *InsertCommand) Usage() string {
 	return strings.TrimLeft(`
-usage: bolt insert PATH BUCKET_NAME KEY VALUE
+usage: bolt insert [-no-overwrite | -if-absent | -if-present] [-in FILE]
+                   [-key-type TYPE] [-value-type TYPE] [-input-encoding ENCODING]
+                   [-key-encoding ENCODING] [-value-encoding ENCODING]
+                   [-expand] [-strict-env] [-expire DURATION] [-create-bucket]
+                   [-no-sync] [-stats] PATH BUCKET_NAME KEY [VALUE]
+       bolt insert -seq [options] PATH BUCKET_NAME [VALUE]
 
-Insert add a pair of key-value into the bucket
+Insert add a pair of key-value into the bucket. An existing value for the
+key is overwritten. BUCKET_NAME may be a path to a nested bucket, such as
+parent/child.
+
+Additional options include:
+
+	-no-overwrite
+		Fail with "key already exists" instead of overwriting an
+		existing value.
+	-if-absent, -if-present
+		Only insert if the key does not exist yet, or only if it
+		already exists. Otherwise nothing is written and the command
+		exits with status 4 without printing an error. The check and
+		the write happen in the same transaction.
+	-in FILE
+		Read the value from FILE instead of the VALUE argument. The
+		contents are stored as-is, so binary data is preserved.
+	-key-type TYPE, -value-type TYPE
+		Encode KEY or VALUE as TYPE before inserting. TYPE is one of
+		string (the default), hex, base64, uint32be or uint64be, e.g.
+		-key-type uint64be stores 42 as 8 big-endian bytes. Use hex
+		or base64 to insert arbitrary binary data, e.g. -key-type hex
+		00ff with a plain text VALUE.
+	-input-encoding ENCODING
+		Decode both KEY and VALUE as raw, hex or base64. This sets
+		-key-type and -value-type together, raw being string.
+	-key-encoding ENCODING, -value-encoding ENCODING
+		Decode only KEY or only VALUE as raw, hex or base64.
+	-seq
+		Use the bucket's next sequence number, encoded as 8
+		big-endian bytes, as the key and print it. KEY is omitted.
+		List such keys with -key-type uint64be.
+	-expand
+		Replace ${VAR} and $VAR references in VALUE with the values
+		of environment variables before inserting, e.g. to load
+		secrets into a templated config. Undefined variables expand
+		to the empty string. Off by default, so values are stored
+		literally.
+	-strict-env
+		Like -expand, but fail if VALUE references a variable that
+		is not defined.
+	-expire DURATION
+		Also record that the key expires after DURATION, e.g. 24h,
+		in the companion bucket BUCKET_NAME__exp, which is created if
+		needed. Both writes happen in the same transaction. The
+		expiry is stored as an RFC3339 timestamp; remove expired keys
+		with "bolt expire-sweep". Inserting the key again without
+		-expire removes its expiry.
+	-create-bucket
+		Create BUCKET_NAME, and any missing parents of a nested path,
+		if it doesn't exist instead of failing with "bucket not
+		found". Together with -touch this populates a new database
+		in one go:
+
+			bolt insert -touch -create-bucket new.db config k v
+
+		The bucket is created in the same transaction as the pair,
+		so it isn't left behind if the insert fails.
+	-no-sync
+		Skip the fsync after committing. This speeds up loading a
+		throwaway database but a crash can lose or corrupt data, so
+		never use it on production data.
+	-stats
+		Print the write statistics of the transaction (pages
+		allocated, splits, spills, writes) to stderr.
 `, "\n")
 }
 
@@ -327,51 +1952,78 @@ func newDeleteCommand(m *Main) *DeleteCommand {
 func (cmd *DeleteCommand) Run(args ...string) error {
 	// Parse flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
 	help := fs.Bool("h", false, "")
-	if err := fs.Parse(args); err != nil {
+	keyType := fs.String("key-type", typeString, "")
+	fs.Var(encodingFlag{keyType}, "input-encoding", "")
+	fs.Var(encodingFlag{keyType}, "key-encoding", "")
+	dryRun := fs.Bool("dry-run", false, "")
+	if err := parseFlags(fs, args); err != nil {
 		return err
 	} else if *help {
 		fmt.Fprintln(cmd.Stderr, cmd.Usage())
 		return ErrUsage
+	} else if err := exclusive(fs, "input-encoding", "key-type", "key-encoding"); err != nil {
+		return err
+	} else if err := checkType(*keyType); err != nil {
+		return err
 	}
 
-	// Require database path.
-	path := fs.Arg(0)
-	if path == "" {
-		return ErrPathRequired
-	} else if _, err := os.Stat(path); os.IsNotExist(err) {
-		return ErrFileNotFound
+	cmd.maxArgs = 2
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+	if key == "" {
+		return ErrKeyRequired
+	}
+	k, err := encodeKey(*keyType, key)
+	if err != nil {
+		return err
 	}
 
 	// Open database.
-	db, err := bolt.Open(path, 0666, nil)
+	db, err := cmd.openDB(cmd.path(fs), *dryRun)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = db.Close() }()
 
-	bucketName := fs.Arg(1)
-	if bucketName == "" {
-		return ErrBucketRequired
-	}
-	key := fs.Arg(2)
-	if key == "" {
-		return ErrKeyRequired
+	if *dryRun {
+		return db.View(func(tx *bolt.Tx) error {
+			bucket, err := boltview.LookupBucket(tx, bucketName)
+			if err != nil {
+				return err
+			}
+			if bucket.Get(k) != nil {
+				fmt.Fprintf(cmd.Stdout, "- %s\t%s\n", bucketName, key)
+			}
+			return nil
+		})
 	}
-
-	return db.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return ErrBucketNotFound
-		}
-		return bucket.Delete([]byte(key))
-	})
+	return boltview.Delete(db, bucketName, k)
 }
 
 func (cmd *DeleteCommand) Usage() string {
 	return strings.TrimLeft(`
-usage: bolt delete PATH BUCKET_NAME KEY
+usage: bolt delete [-key-type TYPE | -key-encoding ENCODING] [-dry-run]
+                   PATH BUCKET_NAME KEY
 
 Delete delete a pair of key-value from the bucket
+
+Additional options include:
+
+	-key-type TYPE
+		Encode KEY as TYPE, as in insert, so binary keys can be
+		given, e.g. -key-type hex 00ff.
+	-key-encoding ENCODING
+		Decode KEY as raw, hex or base64, the older spelling of
+		-key-type for binary keys. -input-encoding is accepted too.
+	-dry-run
+		Print "- BUCKET<TAB>KEY" if the key would be deleted, without
+		modifying the database. The database is opened read-only.
 `, "\n")
 }
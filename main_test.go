@@ -0,0 +1,2241 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/boltdb/bolt"
+)
+
+// testMain is a Main whose standard streams are buffers.
+type testMain struct {
+	*Main
+	Stdin  bytes.Buffer
+	Stdout bytes.Buffer
+	Stderr bytes.Buffer
+}
+
+func newTestMain() *testMain {
+	m := &testMain{Main: NewMain()}
+	m.Main.Stdin = &m.Stdin
+	m.Main.Stdout = &m.Stdout
+	m.Main.Stderr = &m.Stderr
+	return m
+}
+
+// run runs a command line on a fresh Main and returns its output and the
+// exit status the error maps to.
+func run(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	m := newTestMain()
+	m.Stdin.WriteString(stdin)
+	err := m.Run(args...)
+	return m.Stdout.String(), exitCode(err)
+}
+
+// tempDB returns the path of a new database holding the given buckets,
+// each with "key=value" pairs.
+func tempDB(t *testing.T, buckets map[string][]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for name, pairs := range buckets {
+			b, err := tx.CreateBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+			for _, p := range pairs {
+				kv := strings.SplitN(p, "=", 2)
+				if err := b.Put([]byte(kv[0]), []byte(kv[1])); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseFlags(t *testing.T) {
+	for _, tt := range []struct {
+		args       []string
+		limit      int
+		positional []string
+		err        bool
+	}{
+		{args: []string{"db", "b"}, positional: []string{"db", "b"}},
+		{args: []string{"-limit", "3", "db", "b"}, limit: 3, positional: []string{"db", "b"}},
+		{args: []string{"db", "b", "-limit", "3"}, limit: 3, positional: []string{"db", "b"}},
+		{args: []string{"db", "b", "--limit=3"}, limit: 3, positional: []string{"db", "b"}},
+		{args: []string{"db", "b", "k", "-1"}, positional: []string{"db", "b", "k", "-1"}},
+		{args: []string{"db", "b", "-k", "-v=x"}, positional: []string{"db", "b", "-k", "-v=x"}},
+		{args: []string{"db", "--", "-limit", "3"}, positional: []string{"db", "-limit", "3"}},
+		{args: []string{"db", "-"}, positional: []string{"db", "-"}},
+		{args: []string{"-nope", "db"}, err: true},
+	} {
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		limit := fs.Int("limit", 0, "")
+		err := parseFlags(fs, tt.args)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.args)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%q: %s", tt.args, err)
+			continue
+		}
+		if *limit != tt.limit {
+			t.Errorf("%q: limit = %d, want %d", tt.args, *limit, tt.limit)
+		}
+		if got := fs.Args(); !reflect.DeepEqual(got, tt.positional) {
+			t.Errorf("%q: args = %q, want %q", tt.args, got, tt.positional)
+		}
+	}
+}
+
+// Values starting with "-" are written as they were before flags could
+// follow the positional arguments.
+func TestInsert_DashValue(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	if _, code := run(t, "", "insert", path, "b", "k", "-1"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if out, _ := run(t, "", "get", path, "b", "k"); out != "-1\n" {
+		t.Fatalf("get = %q, want %q", out, "-1\n")
+	}
+}
+
+// A field without an encoding still follows -key-type or -value-type when
+// the other field has one.
+func TestLoad_MixedEncoding(t *testing.T) {
+	path := tempDB(t, nil)
+	in := `{"key":"6b31","value":"djE=","value_encoding":"base64"}` + "\n" +
+		`{"key":"azI=","key_encoding":"base64","value":"7632"}` + "\n"
+	if _, code := run(t, in, "load", "-key-type", "hex", "-value-type", "hex", path, "a/b"); code != 0 {
+		t.Fatalf("load: exit status %d", code)
+	}
+	for key, want := range map[string]string{"k1": "v1\n", "k2": "v2\n"} {
+		if out, _ := run(t, "", "get", path, "a/b", key); out != want {
+			t.Errorf("get %s = %q, want %q", key, out, want)
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	path := tempDB(t, nil)
+	if out, code := run(t, "key,value\nk1,v1\nk2,v2\n", "import-csv", "-header", path, "b"); code != 0 || out != "loaded 2 pairs\n" {
+		t.Fatalf("import-csv = %q, exit status %d", out, code)
+	}
+	if out, _ := run(t, "", "get", path, "b", "k2"); out != "v2\n" {
+		t.Fatalf("get = %q, want %q", out, "v2\n")
+	}
+}
+
+// A failed insert with -create-bucket doesn't leave the bucket behind.
+// Importing in batches writes everything, whichever format it reads.
+func TestImport_BatchSize(t *testing.T) {
+	pairs := make([]string, 10000)
+	for i := range pairs {
+		pairs[i] = fmt.Sprintf("k%05d=v%d", i, i)
+	}
+	src := tempDB(t, map[string][]string{"b": pairs})
+	for _, format := range []string{"-stream=false", "-stream"} {
+		export, code := run(t, "", "export", format, src)
+		if code != 0 {
+			t.Fatalf("export %s: exit status %d", format, code)
+		}
+		dst := tempDB(t, nil)
+		if out, code := run(t, export, "import", "-batch-size", "1000", dst); code != 0 || out != "imported 10000 keys, skipped 0 existing keys\n" {
+			t.Fatalf("import %s = %q, exit status %d", format, out, code)
+		}
+		if got, want := contents(t, dst), contents(t, src); !reflect.DeepEqual(got, want) {
+			t.Fatalf("import %s: got %d lines, want %d", format, len(got), len(want))
+		}
+	}
+	if _, code := run(t, "", "import", "-batch-size", "-1", tempDB(t, nil)); code == 0 {
+		t.Fatal("import -batch-size -1: expected an error")
+	}
+}
+
+// A gzip export decompresses to the plain one, whether it is asked for with
+// -gzip or implied by the name of the output file.
+func TestExport_Gzip(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k1=v1", "k2=v2"}, "b": {"k=v"}})
+	plain, code := run(t, "", "export", path)
+	if code != 0 {
+		t.Fatalf("export: exit status %d", code)
+	}
+
+	gunzip := func(data string) string {
+		t.Helper()
+		zr, err := gzip.NewReader(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	out, code := run(t, "", "export", "-gzip", path)
+	if code != 0 {
+		t.Fatalf("export -gzip: exit status %d", code)
+	} else if got := gunzip(out); got != plain {
+		t.Fatalf("export -gzip decompresses to %q, want %q", got, plain)
+	}
+
+	file := filepath.Join(t.TempDir(), "backup.json.gz")
+	if _, code := run(t, "", "export", "-o", file, path); code != 0 {
+		t.Fatalf("export -o %s: exit status %d", file, code)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	} else if got := gunzip(string(data)); got != plain {
+		t.Fatalf("%s decompresses to %q, want %q", file, got, plain)
+	}
+}
+
+// An interrupt leaves no partial output behind and exits with status 130.
+func TestInterrupt(t *testing.T) {
+	notify := notifyContext
+	t.Cleanup(func() { notifyContext = notify })
+	notifyContext = func(parent context.Context, _ ...os.Signal) (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(parent)
+		cancel()
+		return ctx, cancel
+	}
+
+	path := tempDB(t, map[string][]string{"a": {"k=v"}, "b": {"k=v"}})
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.json")
+	if err := os.WriteFile(existing, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"export", "-o", filepath.Join(dir, "new.json"), path},
+		{"export", "-o", existing, path},
+		{"salvage", path, filepath.Join(dir, "copy.db")},
+	} {
+		if _, code := run(t, "", args...); code != 130 {
+			t.Errorf("%q: exit status %d, want 130", args, code)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 1 || entries[0].Name() != "existing.json" {
+		t.Fatalf("files left behind: %v", entries)
+	}
+	if b, err := os.ReadFile(existing); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "old\n" {
+		t.Fatalf("existing file = %q, want it unchanged", b)
+	}
+}
+
+func TestInsert_CreateBucket(t *testing.T) {
+	// The expiry can't be written because a/b__exp is a key.
+	path := tempDB(t, map[string][]string{"a": {"b__exp=x"}})
+	if _, code := run(t, "", "insert", "-create-bucket", "-expire", "1h", path, "a/b", "k", "v"); code != 1 {
+		t.Fatalf("insert -expire: exit status %d, want 1", code)
+	}
+	if out, _ := run(t, "", "buckets", "-names-only", "-r", path); out != "a\n" {
+		t.Fatalf("buckets = %q, want only a", out)
+	}
+	if _, code := run(t, "", "insert", "-create-bucket", path, "a/b", "k", "v"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if out, _ := run(t, "", "get", path, "a/b", "k"); out != "v\n" {
+		t.Fatalf("get = %q, want %q", out, "v\n")
+	}
+}
+
+// With BOLT_DB set, PATH is given explicitly when there are more arguments
+// than the command takes after it, whether or not the file exists yet.
+func TestBoltDBEnv(t *testing.T) {
+	env := tempDB(t, map[string][]string{"b": {"k=env"}})
+	t.Setenv("BOLT_DB", env)
+
+	if out, code := run(t, "", "buckets", "-names-only"); code != 0 || out != "b\n" {
+		t.Fatalf("buckets = %q, exit status %d", out, code)
+	}
+	if out, _ := run(t, "", "get", "b", "k"); out != "env\n" {
+		t.Fatalf("get = %q, want %q", out, "env\n")
+	}
+
+	path := filepath.Join(t.TempDir(), "new.db")
+	if _, code := run(t, "", "insert", "-touch", "-create-bucket", path, "b", "k", "new"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if out, _ := run(t, "", "get", "-db", path, "b", "k"); out != "new\n" {
+		t.Fatalf("get new.db = %q, want %q", out, "new\n")
+	}
+	if out, _ := run(t, "", "get", "b", "k"); out != "env\n" {
+		t.Fatalf("get BOLT_DB = %q, want %q", out, "env\n")
+	}
+}
+
+// An existing file given as PATH wins over BOLT_DB even when the command
+// would also accept the arguments without it.
+func TestBoltDBEnv_ExplicitPath(t *testing.T) {
+	t.Setenv("BOLT_DB", tempDB(t, map[string][]string{"a": {"k=env"}}))
+	path := tempDB(t, map[string][]string{"bb": {"k=explicit"}})
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"buckets", "-names-only", path}, "bb\n"},
+		{[]string{"get", path, "bb", "k"}, "explicit\n"},
+		{[]string{"get", "a", "k"}, "env\n"},
+	} {
+		if out, code := run(t, "", tt.args...); code != 0 || out != tt.want {
+			t.Errorf("%q = %q, exit status %d, want %q", tt.args, out, code, tt.want)
+		}
+	}
+	if got, want := listKeys(t, path, "bb"), []string{"k"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("list = %q, want %q", got, want)
+	}
+}
+
+// Replaying a dump line by line through Main.Run recreates the database,
+// including nested buckets, sequences, binary data and names starting with
+// "-".
+func TestDump_Restore(t *testing.T) {
+	src := tempDB(t, map[string][]string{"-b": {"-k=-v", "k=v"}})
+	db, err := bolt.Open(src, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		parent := tx.Bucket([]byte("-b"))
+		if err := parent.SetSequence(7); err != nil {
+			return err
+		}
+		child, err := parent.CreateBucket([]byte("child"))
+		if err != nil {
+			return err
+		}
+		return child.Put([]byte{0, 0xff}, []byte("it's\n"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	script, code := run(t, "", "dump", src)
+	if code != 0 {
+		t.Fatalf("dump: exit status %d", code)
+	}
+	// The script touches the database before running the commands.
+	dst := filepath.Join(t.TempDir(), "restored.db")
+	if err := os.WriteFile(dst, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(line, `"$BOLT" `) {
+			continue
+		}
+		args := shellWords(t, strings.Replace(line, `"$DB"`, dst, 1))
+		if _, code := run(t, "", args[1:]...); code != 0 {
+			t.Fatalf("%s: exit status %d", line, code)
+		}
+	}
+
+	if got, want := contents(t, dst), contents(t, src); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored = %q, want %q", got, want)
+	}
+}
+
+// shellWords splits a line of a dump script as sh would. Only the quoting
+// used by dump is supported.
+func shellWords(t *testing.T, line string) []string {
+	t.Helper()
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == ' ':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			inWord = false
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(line[i+1:], c)
+			if j < 0 {
+				t.Fatalf("unterminated quote in %q", line)
+			}
+			word.WriteString(line[i+1 : i+1+j])
+			i += j + 1
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// contents returns every bucket, sequence and pair in the database as
+// lines of text.
+func contents(t *testing.T, path string) []string {
+	t.Helper()
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var lines []string
+	var walk func(path string, b *bolt.Bucket) error
+	walk = func(path string, b *bolt.Bucket) error {
+		lines = append(lines, fmt.Sprintf("bucket %q seq %d", path, b.Sequence()))
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return walk(path+"/"+string(k), b.Bucket(k))
+			}
+			lines = append(lines, fmt.Sprintf("%q %q = %q", path, k, v))
+			return nil
+		})
+	}
+	if err := db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return walk(string(name), b)
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+// Binary keys and values are given with -key-type and -value-type on every
+// command that writes them.
+func TestKeyType_Hex(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": nil, "b": nil})
+	for _, args := range [][]string{
+		{"insert", "-key-type", "hex", "-value-type", "hex", path, "a", "00ff", "6869"},
+		{"update", "-key-type", "hex", "-value-type", "base64", path, "a", "00ff", "aGkh"},
+		{"move", "-key-type", "hex", "-new-key", "01", path, "a", "00ff", "b"},
+	} {
+		if _, code := run(t, "", args...); code != 0 {
+			t.Fatalf("%q: exit status %d", args, code)
+		}
+	}
+	if out, _ := run(t, "", "get", "-key-type", "hex", path, "b", "01"); out != "hi!\n" {
+		t.Fatalf("get = %q, want %q", out, "hi!\n")
+	}
+	if _, code := run(t, "", "delete", "-key-type", "hex", path, "b", "01"); code != 0 {
+		t.Fatalf("delete: exit status %d", code)
+	}
+	if out, _ := run(t, "", "list", "-quiet", path, "b"); out != "" {
+		t.Fatalf("list = %q, want nothing", out)
+	}
+}
+
+// Read commands share the lock with other readers unless given -rw.
+func TestReadOnly_RW(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	other := tempDB(t, nil)
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if out, code := run(t, "", "get", "-timeout", "100ms", path, "b", "k"); code != 0 || out != "v\n" {
+		t.Fatalf("get = %q, exit status %d", out, code)
+	}
+	for _, args := range [][]string{
+		{"get", "-rw", "-timeout", "100ms", path, "b", "k"},
+		{"list", "-rw", "-timeout", "100ms", path, "b"},
+		{"buckets", "-rw", "-timeout", "100ms", path},
+		{"diff", "-rw", "-timeout", "100ms", other, path},
+	} {
+		if _, code := run(t, "", args...); code != 5 {
+			t.Errorf("%q: exit status %d, want 5", args, code)
+		}
+	}
+}
+
+// Each poll prints the changes since the previous one, and an interrupt
+// stops the watch without an error.
+func TestWatch(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=v2"}})
+	m := newTestMain()
+	cmd := newWatchCommand(m.Main)
+	tick := make(chan time.Time)
+	cmd.tick = tick
+	done := make(chan error, 1)
+	go func() { done <- cmd.Run(path, "b") }()
+
+	// The ticks are unbuffered, so each send waits for the previous poll
+	// to finish. The changes show up on the first or second poll.
+	tick <- time.Now()
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("b"))
+		if err := b.Delete([]byte("k1")); err != nil {
+			return err
+		} else if err := b.Put([]byte("k2"), []byte("new")); err != nil {
+			return err
+		} else if _, err := b.CreateBucket([]byte("sub")); err != nil {
+			return err
+		}
+		return b.Put([]byte("k3"), []byte("v3"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	tick <- time.Now()
+	tick <- time.Now()
+	cmd.interrupt <- os.Interrupt
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Stdout.String(), "- k1\n~ k2\tnew\n+ k3\tv3\n+ sub\t[bucket]\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+// Watch waits for the lock as long as -timeout says, not -interval.
+func TestWatch_Locked(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	err = newTestMain().Run("watch", "-interval", "1h", "-timeout", "50ms", path, "b")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("watch: error %v, want %v", err, ErrLocked)
+	}
+}
+
+func TestBuckets_NamesOnly(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k=v"}, "b": nil})
+	if out, code := run(t, "", "buckets", "-names-only", path); code != 0 || out != "a\nb\n" {
+		t.Fatalf("buckets = %q, exit status %d", out, code)
+	}
+	empty := tempDB(t, nil)
+	if out, code := run(t, "", "buckets", "-names-only", empty); code != 3 || out != "" {
+		t.Fatalf("empty buckets = %q, exit status %d, want no output and 3", out, code)
+	}
+}
+
+func TestInsert_NoOverwrite(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=old"}})
+	m := newTestMain()
+	if err := m.Run("insert", "-no-overwrite", path, "b", "k", "new"); err != ErrKeyExists {
+		t.Fatalf("insert -no-overwrite: err = %v, want %v", err, ErrKeyExists)
+	}
+	if out, _ := run(t, "", "get", path, "b", "k"); out != "old\n" {
+		t.Fatalf("get = %q, want %q", out, "old\n")
+	}
+	if _, code := run(t, "", "insert", "-no-overwrite", path, "b", "k2", "v2"); code != 0 {
+		t.Fatalf("insert -no-overwrite new key: exit status %d", code)
+	}
+	if _, code := run(t, "", "insert", path, "b", "k", "new"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if out, _ := run(t, "", "get", path, "b", "k"); out != "new\n" {
+		t.Fatalf("get = %q, want %q", out, "new\n")
+	}
+}
+
+// listKeys runs list -quiet with args and returns the listed keys.
+func listKeys(t *testing.T, args ...string) []string {
+	t.Helper()
+	out, code := run(t, "", append([]string{"list", "-quiet"}, args...)...)
+	if code != 0 {
+		t.Fatalf("list %q: exit status %d", args, code)
+	}
+	var keys []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			keys = append(keys, fields[0])
+		}
+	}
+	return keys
+}
+
+func TestList_After(t *testing.T) {
+	var pairs []string
+	for i := 1; i <= 10; i++ {
+		pairs = append(pairs, fmt.Sprintf("k%02d=v%d", i, i))
+	}
+	path := tempDB(t, map[string][]string{"b": pairs})
+	if got, want := listKeys(t, "-after", "k05", "-limit", "3", path, "b"), []string{"k06", "k07", "k08"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-after", "k08", path, "b"), []string{"k09", "k10"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("last page = %q, want %q", got, want)
+	}
+}
+
+func TestSetMany(t *testing.T) {
+	path := tempDB(t, nil)
+	in := `{"name":"bolt","port":8080,"ratio":0.5,"debug":true,"empty":""}`
+	if _, code := run(t, in, "set-many", path, "config"); code != 0 {
+		t.Fatalf("set-many: exit status %d", code)
+	}
+	for key, want := range map[string]string{
+		"name":  "bolt\n",
+		"port":  "8080\n",
+		"ratio": "0.5\n",
+		"debug": "true\n",
+		"empty": "\n",
+	} {
+		if out, code := run(t, "", "get", path, "config", key); code != 0 || out != want {
+			t.Errorf("get %s = %q, exit status %d, want %q", key, out, code, want)
+		}
+	}
+	if _, code := run(t, "{", "set-many", path, "config"); code != 1 {
+		t.Fatalf("set-many invalid json: exit status %d, want 1", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=v2"}, "same": {"k=v"}})
+	b := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=v2", "k3=v3"}, "same": {"k=v"}})
+	if out, code := run(t, "", "diff", a, b); code != 0 || out != "+ b\tk3\tv3\n" {
+		t.Fatalf("diff = %q, exit status %d", out, code)
+	}
+	if out, _ := run(t, "", "diff", b, a); out != "- b\tk3\n" {
+		t.Fatalf("reverse diff = %q, want %q", out, "- b\tk3\n")
+	}
+	if out, _ := run(t, "", "diff", "-bucket", "same", a, b); out != "" {
+		t.Fatalf("diff -bucket same = %q, want nothing", out)
+	}
+	if _, code := run(t, "", "diff", "-bucket", "missing", a, b); code != 1 {
+		t.Fatalf("diff -bucket missing: exit status %d, want 1", code)
+	}
+
+	// Nested buckets are compared under their path.
+	a = tempDB(t, nil)
+	b = tempDB(t, nil)
+	for _, tt := range []struct {
+		path string
+		args []string
+	}{
+		{a, []string{"p/c", "k", "v1"}},
+		{a, []string{"p/old", "k", "v"}},
+		{b, []string{"p/c", "k", "v2"}},
+		{b, []string{"p/c", "extra", "x"}},
+		{b, []string{"p/c/new", "k", "v"}},
+	} {
+		if _, code := run(t, "", append([]string{"insert", "-create-bucket", tt.path}, tt.args...)...); code != 0 {
+			t.Fatalf("insert %q: exit status %d", tt.args, code)
+		}
+	}
+	want := "+ p/c\textra\tx\n~ p/c\tk\tv2\n+ p/c/new\n+ p/c/new\tk\tv\n- p/old\n- p/old\tk\n"
+	if out, code := run(t, "", "diff", a, b); code != 0 || out != want {
+		t.Fatalf("nested diff = %q, exit status %d, want %q", out, code, want)
+	}
+	if out, _ := run(t, "", "diff", "-bucket", "p/c/new", a, b); out != "+ p/c/new\n+ p/c/new\tk\tv\n" {
+		t.Fatalf("diff -bucket p/c/new = %q", out)
+	}
+}
+
+// A binary key given in hex is stored as bytes and found again by get.
+func TestInsert_HexKey(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	if _, code := run(t, "", "insert", "-input-encoding", "hex", path, "b", "00ff01", "deadbeef"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if out, _ := run(t, "", "get", "-key-type", "hex", "-value-type", "hex", path, "b", "00ff01"); out != "deadbeef\n" {
+		t.Fatalf("get = %q, want %q", out, "deadbeef\n")
+	}
+	want := []string{`bucket "b" seq 0`, fmt.Sprintf("%q %q = %q", "b", "\x00\xff\x01", "\xde\xad\xbe\xef")}
+	if got := contents(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"insert", "-input-encoding", "hex", path, "b", "zz", "00"}, "invalid hex key"},
+		{[]string{"insert", "-input-encoding", "base32", path, "b", "k", "v"}, "must be raw, hex or base64"},
+		{[]string{"insert", "-input-encoding", "hex", "-key-type", "hex", path, "b", "00", "00"}, "cannot be used together"},
+		{[]string{"delete", "-input-encoding", "hex", "-key-type", "string", path, "b", "00"}, "cannot be used together"},
+	} {
+		m := newTestMain()
+		if err := m.Run(tt.args...); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: err = %v, want %q", tt.args, err, tt.err)
+		}
+	}
+
+	// The other commands taking binary arguments accept it too.
+	for _, args := range [][]string{
+		{"update", "-input-encoding", "base64", path, "b", "AP8B", "aGkh"},
+		{"move", "-input-encoding", "hex", "-new-key", "01", path, "b", "00ff01", "b"},
+	} {
+		if _, code := run(t, "", args...); code != 0 {
+			t.Fatalf("%q: exit status %d", args, code)
+		}
+	}
+	if out, _ := run(t, "", "get", "-key-type", "hex", path, "b", "01"); out != "hi!\n" {
+		t.Fatalf("get 01 = %q, want %q", out, "hi!\n")
+	}
+	if _, code := run(t, "", "delete", "-input-encoding", "hex", path, "b", "01"); code != 0 {
+		t.Fatalf("delete: exit status %d", code)
+	}
+	if got := contents(t, path); !reflect.DeepEqual(got, want[:1]) {
+		t.Fatalf("contents after delete = %q, want %q", got, want[:1])
+	}
+}
+
+func TestBatch(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k=v"}})
+	script := "mkbucket u\nput u k1 hello world\n# comment\n\nput u k2 v2\ndel a k\n"
+	if out, code := run(t, script, "batch", path); code != 0 || out != "applied 4 operations\n" {
+		t.Fatalf("batch = %q, exit status %d", out, code)
+	}
+	want := []string{
+		`bucket "a" seq 0`,
+		`bucket "u" seq 0`,
+		`"u" "k1" = "hello world"`,
+		`"u" "k2" = "v2"`,
+	}
+	if got := contents(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+// A failing line undoes the lines before it, and a syntax error stops the
+// script before anything is applied.
+func TestBatch_Rollback(t *testing.T) {
+	path := tempDB(t, map[string][]string{"u": {"k=v"}})
+	for _, script := range []string{
+		"put u k2 v2\ndel u k\nput missing k v\n",
+		"put u k2 v2\ndel u k\nfrob\n",
+		"put u k2 v2\ndel u\n",
+	} {
+		m := newTestMain()
+		m.Stdin.WriteString(script)
+		if err := m.Run("batch", path); err == nil || !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("%q: err = %v, want a line error", script, err)
+		}
+		if got, want := contents(t, path), []string{`bucket "u" seq 0`, `"u" "k" = "v"`}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q: contents = %q, want %q", script, got, want)
+		}
+	}
+}
+
+func TestList_Filter(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"user:1=short", "user:2=a longer value", "group:1=another long value"}})
+	if got, want := listKeys(t, "-filter", "len(value) > 10", path, "b"), []string{"group:1", "user:2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("length filter = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-filter", `key startswith "user:"`, path, "b"), []string{"user:1", "user:2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("prefix filter = %q, want %q", got, want)
+	}
+
+	// The expression is checked before the bucket is looked up.
+	m := newTestMain()
+	if err := m.Run("list", "-filter", "len(value) >", path, "missing"); err == nil || !strings.HasPrefix(err.Error(), "invalid -filter") {
+		t.Fatalf("err = %v, want an invalid -filter error", err)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	for _, tt := range []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{[]string{"-json-errors", "get", path, "missing", "k"}, 1, `{"error":"bucket not found","code":1}` + "\n"},
+		{[]string{"-json-errors", "buckets", "-names-only", tempDB(t, nil)}, 3, `{"error":"no buckets","code":3}` + "\n"},
+		{[]string{"-json-errors", "get", path, "b", "k"}, 0, ""},
+		{[]string{"get", path, "missing", "k"}, 1, ""},
+	} {
+		m := newTestMain()
+		if code := m.report(m.Run(tt.args...)); code != tt.code {
+			t.Errorf("%q: exit status %d, want %d", tt.args, code, tt.code)
+		}
+		if got := m.Stderr.String(); got != tt.stderr {
+			t.Errorf("%q: stderr = %q, want %q", tt.args, got, tt.stderr)
+		}
+	}
+}
+
+// -key-encoding and -value-encoding select binary keys and values on the
+// commands that read and write them.
+func TestKeyEncoding(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	if _, code := run(t, "", "insert", "-key-encoding", "hex", "-value-encoding", "base64", path, "b", "00ff", "aGkh"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if out, _ := run(t, "", "get", "-key-encoding", "hex", "-value-encoding", "hex", path, "b", "00ff"); out != "686921\n" {
+		t.Fatalf("get = %q, want %q", out, "686921\n")
+	}
+	if got, want := listKeys(t, "-key-encoding", "hex", "-prefix", "00", path, "b"), []string{"00ff"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("list = %q, want %q", got, want)
+	}
+	m := newTestMain()
+	if err := m.Run("get", "-key-encoding", "hex", "-format-key", "raw", path, "b", "00ff"); err == nil || !strings.Contains(err.Error(), "cannot be used together") {
+		t.Fatalf("get -key-encoding -format-key: err = %v", err)
+	}
+	if _, code := run(t, "", "delete", "-key-encoding", "hex", path, "b", "00ff"); code != 0 {
+		t.Fatalf("delete: exit status %d", code)
+	}
+	if got := listKeys(t, path, "b"); len(got) != 0 {
+		t.Fatalf("list after delete = %q, want none", got)
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	for _, tt := range []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1 << 20, "1.0 MiB"},
+		{1 << 30, "1.0 GiB"},
+	} {
+		if got := humanizeBytes(tt.n); got != tt.want {
+			t.Errorf("humanizeBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+// brokenPipe is a stdout whose reader has gone away.
+type brokenPipe struct{}
+
+func (brokenPipe) Write(p []byte) (int, error) { return 0, syscall.EPIPE }
+
+// A closed pipe ends a read command quietly with the SIGPIPE status.
+func TestList_BrokenPipe(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=v2"}})
+	m := newTestMain()
+	m.Main.Stdout = brokenPipe{}
+	err := m.Run("list", path, "b")
+	if code := m.report(err); code != 141 {
+		t.Fatalf("list: exit status %d (%v), want 141", code, err)
+	} else if m.Stderr.Len() != 0 {
+		t.Fatalf("list printed %q", m.Stderr.String())
+	}
+}
+
+// Conflicting output formats are rejected by name before the database is
+// opened.
+func TestList_FormatConflict(t *testing.T) {
+	for _, args := range [][]string{
+		{"-json", "-csv"},
+		{"-format", "text", "-json"},
+	} {
+		m := newTestMain()
+		err := m.Run(append(append([]string{"list"}, args...), "missing.db", "b")...)
+		if err == nil {
+			t.Fatalf("list %q: expected an error", args)
+		}
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-") && !strings.Contains(err.Error(), arg) {
+				t.Errorf("list %q: error %q doesn't name %s", args, err, arg)
+			}
+		}
+	}
+}
+
+func TestList_CSV(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=a,b"}})
+	if out, code := run(t, "", "list", "-csv", path, "b"); code != 0 || out != "key,value\nk1,v1\nk2,\"a,b\"\n" {
+		t.Fatalf("list -csv = %q, exit status %d", out, code)
+	}
+	if out, code := run(t, "", "list", "-json", path, "b"); code != 0 || !strings.HasPrefix(out, `{"bucket":"b","key":"k1","value":"v1"}`) {
+		t.Fatalf("list -json = %q, exit status %d", out, code)
+	}
+}
+
+func TestExists(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}, "empty": nil})
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{path, "b"}, 0},
+		{[]string{path, "empty"}, 0},
+		{[]string{path, "b", "k"}, 0},
+		{[]string{path, "b", "missing"}, 3},
+		{[]string{path, "missing"}, 3},
+		{[]string{path, "missing", "k"}, 3},
+		{[]string{filepath.Join(t.TempDir(), "missing.db"), "b"}, 1},
+	} {
+		out, code := run(t, "", append([]string{"exists"}, tt.args...)...)
+		if code != tt.code {
+			t.Errorf("exists %q: exit status %d, want %d", tt.args, code, tt.code)
+		} else if out != "" {
+			t.Errorf("exists %q printed %q", tt.args, out)
+		}
+	}
+}
+
+// A dry run reports what it would change but leaves the database as it
+// was.
+func TestDryRun(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k1=v1", "k2=v2"}, "b": nil})
+	before, code := run(t, "", "checksum", path)
+	if code != 0 {
+		t.Fatalf("checksum: exit status %d", code)
+	}
+	if out, code := run(t, "", "delete", "-dry-run", path, "a", "k1"); code != 0 || out != "- a\tk1\n" {
+		t.Fatalf("delete -dry-run = %q, exit status %d", out, code)
+	}
+	for _, args := range [][]string{
+		{"move", "-dry-run", path, "a", "k2", "b"},
+		{"truncate", "-dry-run", path, "a"},
+	} {
+		if _, code := run(t, "", args...); code != 0 {
+			t.Fatalf("%q: exit status %d", args, code)
+		}
+	}
+	if after, _ := run(t, "", "checksum", path); after != before {
+		t.Fatalf("checksum after dry runs = %q, want %q", after, before)
+	}
+}
+
+// Without a KEY, get looks up every key read from stdin.
+func TestGet_Stdin(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k1=v1", "k3=v3"}})
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "k1\tv1\nk2\t<null>\nk3\tv3\n"},
+		{[]string{"-skip-missing"}, "k1\tv1\nk3\tv3\n"},
+		{[]string{"-default", "none"}, "k1\tv1\nk2\tnone\nk3\tv3\n"},
+	} {
+		args := append(append([]string{"get"}, tt.args...), path, "b")
+		if out, code := run(t, "k1\nk2\nk3\n", args...); code != 0 || out != tt.want {
+			t.Errorf("%q = %q, exit status %d, want %q", args, out, code, tt.want)
+		}
+	}
+}
+
+// A bucket listed as CSV, with binary data hex encoded, loads back into
+// the same pairs.
+func TestImportCSV_RoundTrip(t *testing.T) {
+	src := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=a,b", "\x00\xff=\xfe\n"}})
+	csv, code := run(t, "", "list", "-csv", "-key-type", "hex", "-value-type", "hex", src, "b")
+	if code != 0 {
+		t.Fatalf("list -csv: exit status %d", code)
+	}
+	dst := tempDB(t, nil)
+	if out, code := run(t, csv, "import-csv", "-header", "-key-type", "hex", "-value-type", "hex", dst, "b"); code != 0 || out != "loaded 3 pairs\n" {
+		t.Fatalf("import-csv = %q, exit status %d", out, code)
+	}
+	if got, want := contents(t, dst), contents(t, src); !reflect.DeepEqual(got, want) {
+		t.Fatalf("import-csv wrote %q, want %q", got, want)
+	}
+}
+
+// Each -seq insert prints the key it generated, counting up from 1.
+func TestInsert_Seq(t *testing.T) {
+	path := tempDB(t, map[string][]string{"log": nil})
+	for i, value := range []string{"first", "second", "third"} {
+		want := fmt.Sprintf("%d\n", i+1)
+		if out, code := run(t, "", "insert", "-seq", path, "log", value); code != 0 || out != want {
+			t.Fatalf("insert -seq %s = %q, exit status %d, want %q", value, out, code, want)
+		}
+	}
+	if got, want := listKeys(t, "-key-type", "uint64be", path, "log"), []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	if out, _ := run(t, "", "get", "-key-type", "uint64be", path, "log", "2"); out != "second\n" {
+		t.Fatalf("get 2 = %q, want %q", out, "second\n")
+	}
+}
+
+func TestOpenDB_Errors(t *testing.T) {
+	locked := tempDB(t, nil)
+	db, err := bolt.Open(locked, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.db")
+	if err := os.WriteFile(bad, bytes.Repeat([]byte("not a database"), 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		path     string
+		readOnly bool
+		err      error
+		code     int
+	}{
+		{"no path", "", true, ErrPathRequired, 1},
+		{"missing", filepath.Join(dir, "missing.db"), true, ErrFileNotFound, 1},
+		{"missing without -touch", filepath.Join(dir, "missing.db"), false, ErrFileNotFound, 1},
+		{"stdin for writing", "-", false, ErrStdinWrite, 1},
+		{"locked", locked, true, ErrLocked, 5},
+		{"bad file", bad, true, nil, 1},
+	} {
+		cmd := &CommonCommand{timeout: 100 * time.Millisecond}
+		db, err := cmd.openDB(tt.path, tt.readOnly)
+		if err == nil {
+			_ = db.Close()
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: error %q, want %q", tt.name, err, tt.err)
+		}
+		if code := exitCode(err); code != tt.code {
+			t.Errorf("%s: exit status %d, want %d", tt.name, code, tt.code)
+		}
+	}
+}
+
+// -in stores a file's contents as they are, however large.
+func TestInsert_In(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	value := make([]byte, 1<<20)
+	for i := range value {
+		value[i] = byte(i * 7)
+	}
+	file := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(file, value, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, code := run(t, "", "insert", "-in", file, path, "b", "blob"); code != 0 {
+		t.Fatalf("insert -in: exit status %d", code)
+	}
+	if out, code := run(t, "", "get", "-value-type", "hex", path, "b", "blob"); code != 0 || out != hex.EncodeToString(value)+"\n" {
+		t.Fatalf("get: %d bytes, exit status %d, want the %d bytes of the file", len(out), code, len(value))
+	}
+	if _, code := run(t, "", "insert", "-in", file, path, "b", "blob", "value"); code != 1 {
+		t.Fatalf("insert -in with VALUE: exit status %d, want 1", code)
+	}
+}
+
+// -size sums the lengths of the keys and values in each bucket.
+func TestBuckets_Size(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k1=v1", "key2=value2"}, "b": {"k=v"}})
+	want := "NAME     ITEMS    SIZE\n" +
+		"======== ======== ========\n" +
+		"a        2        14\n" +
+		"b        1        2\n"
+	for _, args := range [][]string{
+		{"buckets", "-size", "-bytes", "raw", path},
+		{"buckets", "-size", "-bytes", "raw", "-parallel", "2", path},
+	} {
+		if out, code := run(t, "", args...); code != 0 || out != want {
+			t.Errorf("%q = %q, exit status %d, want %q", args, out, code, want)
+		}
+	}
+	if out, _ := run(t, "", "buckets", "-size", "-format", "json", path); !strings.Contains(out, `"size": 14`) {
+		t.Errorf("buckets -size -format json = %q, want a size of 14 for a", out)
+	}
+}
+
+// -db PATH takes the place of the positional PATH, wherever it is given.
+func TestDBFlag(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	for _, args := range [][]string{
+		{"get", path, "b", "k"},
+		{"get", "-db", path, "b", "k"},
+		{"get", "b", "k", "-db", path},
+		{"get", "--db=" + path, "b", "k"},
+	} {
+		if out, code := run(t, "", args...); code != 0 || out != "v\n" {
+			t.Errorf("%q = %q, exit status %d", args, out, code)
+		}
+	}
+	if _, code := run(t, "", "insert", "-db", path, "b", "k2", "v2"); code != 0 {
+		t.Fatalf("insert -db: exit status %d", code)
+	}
+	if got, want := listKeys(t, "-db", path, "b"), []string{"k", "k2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("list -db = %q, want %q", got, want)
+	}
+}
+
+// -quiet leaves out the header lines of list and buckets.
+func TestQuiet(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	for _, tt := range []struct {
+		args   []string
+		header string
+	}{
+		{[]string{"list", path, "b"}, "KEY"},
+		{[]string{"buckets", path}, "NAME"},
+	} {
+		out, code := run(t, "", tt.args...)
+		if code != 0 || !strings.HasPrefix(out, tt.header) || !strings.Contains(out, "====") {
+			t.Errorf("%q = %q, exit status %d, want a header", tt.args, out, code)
+		}
+		args := append([]string{tt.args[0], "-quiet"}, tt.args[1:]...)
+		out, code = run(t, "", args...)
+		if code != 0 || strings.Contains(out, tt.header) || strings.Contains(out, "====") {
+			t.Errorf("%q = %q, exit status %d, want no header", args, out, code)
+		} else if lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n"); len(lines) != 1 {
+			t.Errorf("%q = %q, want a single row", args, out)
+		}
+	}
+}
+
+// update changes existing keys only.
+func TestUpdate(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	if _, code := run(t, "", "update", path, "b", "k", "v2"); code != 0 {
+		t.Fatalf("update k: exit status %d", code)
+	}
+	m := newTestMain()
+	if err := m.Run("update", path, "b", "typo", "v"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("update typo: error %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, code := run(t, "", "update", path, "missing", "k", "v"); code != 1 {
+		t.Fatalf("update in a missing bucket: exit status %d, want 1", code)
+	}
+	if got, want := contents(t, path), []string{`bucket "b" seq 0`, `"b" "k" = "v2"`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+// -stats prints the write statistics of the insert to stderr.
+func TestInsert_Stats(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	m := newTestMain()
+	if err := m.Run("insert", "-stats", path, "b", "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	stats := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(m.Stderr.String()), "\n") {
+		name, value, _ := strings.Cut(line, ":")
+		stats[name] = strings.TrimSpace(value)
+	}
+	for _, name := range []string{"PageCount", "PageAlloc", "Split", "Spill", "Write", "WriteTime"} {
+		if _, ok := stats[name]; !ok {
+			t.Errorf("stats = %q, missing %s", m.Stderr.String(), name)
+		}
+	}
+	var writes int
+	if _, err := fmt.Sscan(stats["Write"], &writes); err != nil || writes < 1 {
+		t.Errorf("Write = %q, want a positive count", stats["Write"])
+	}
+}
+
+// A database read from stdin as "-" can be inspected like a file, and its
+// temporary copy is removed afterwards.
+func TestStdinDB(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	path := tempDB(t, map[string][]string{"a": {"k=v"}, "b": nil})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, code := run(t, string(data), "buckets", "-names-only", "-"); code != 0 || out != "a\nb\n" {
+		t.Fatalf("buckets - = %q, exit status %d", out, code)
+	}
+	if out, code := run(t, string(data), "get", "-", "a", "k"); code != 0 || out != "v\n" {
+		t.Fatalf("get - = %q, exit status %d", out, code)
+	}
+	if _, code := run(t, string(data), "insert", "-", "a", "k", "v2"); code != 1 {
+		t.Fatalf("insert -: exit status %d, want 1", code)
+	}
+	if entries, err := os.ReadDir(tmp); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Fatalf("temporary files left behind: %v", entries)
+	}
+}
+
+// -wide sizes the first column to the longest key or bucket name, so the
+// second column lines up.
+func TestWide(t *testing.T) {
+	long := strings.Repeat("k", 50)
+	path := tempDB(t, map[string][]string{"b": {long + "=v1", "k=v2"}, "a-long-bucket-name": nil})
+	for _, tt := range []struct {
+		args []string
+		col  int
+	}{
+		{[]string{"list", "-wide", path, "b"}, len(long) + 1},
+		{[]string{"buckets", "-wide", path}, len("a-long-bucket-name") + 1},
+	} {
+		out, code := run(t, "", tt.args...)
+		if code != 0 {
+			t.Fatalf("%q: exit status %d", tt.args, code)
+		}
+		// Every line has its second column right after the widest entry.
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			if len(line) <= tt.col || line[tt.col-1] != ' ' || line[tt.col] == ' ' {
+				t.Errorf("%q: %q doesn't line up at column %d", tt.args, line, tt.col)
+			}
+		}
+	}
+}
+
+// The mmap tuning options change how the database is mapped, not what
+// commands read or write.
+func TestMmapOptions(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	tuning := []string{"-initial-mmap-size", strconv.Itoa(16 << 20)}
+	if runtime.GOOS == "linux" {
+		tuning = append(tuning, "-mmap-flags", strconv.Itoa(0x8000)) // MAP_POPULATE
+	}
+	if _, code := run(t, "", append(append([]string{"insert"}, tuning...), path, "b", "k", "v")...); code != 0 {
+		t.Fatalf("insert %q: exit status %d", tuning, code)
+	}
+	if out, code := run(t, "", append(append([]string{"get"}, tuning...), path, "b", "k")...); code != 0 || out != "v\n" {
+		t.Fatalf("get %q = %q, exit status %d", tuning, out, code)
+	}
+}
+
+func TestFind(t *testing.T) {
+	path := tempDB(t, map[string][]string{
+		"a": {"k=v1", "x=same"},
+		"b": {"y=other"},
+		"c": {"k=v3", "z=same"},
+	})
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{path, "k"}, "a\tv1\nc\tv3\n"},
+		{[]string{path, "missing"}, ""},
+		{[]string{"-value", path, "same"}, "a\tx\nc\tz\n"},
+	} {
+		if out, code := run(t, "", append([]string{"find"}, tt.args...)...); code != 0 || out != tt.want {
+			t.Errorf("find %q = %q, exit status %d, want %q", tt.args, out, code, tt.want)
+		}
+	}
+}
+
+// -r lists nested buckets by path, two levels down.
+func TestBuckets_Recursive(t *testing.T) {
+	path := tempDB(t, map[string][]string{"z": nil})
+	for _, args := range [][]string{
+		{"create-bucket", path, "a/b/c"},
+		{"insert", path, "a/b", "k", "v"},
+		{"insert", path, "a/b/c", "k", "v"},
+	} {
+		if _, code := run(t, "", args...); code != 0 {
+			t.Fatalf("%q: exit status %d", args, code)
+		}
+	}
+	for _, flag := range []string{"-r", "-recursive"} {
+		out, code := run(t, "", "buckets", "-quiet", flag, path)
+		if code != 0 {
+			t.Fatalf("buckets %s: exit status %d", flag, code)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			got = append(got, strings.Join(strings.Fields(line), " "))
+		}
+		if want := []string{"a 4", "a/b 3", "a/b/c 1", "z 0"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("buckets %s = %q, want %q", flag, got, want)
+		}
+	}
+	if out, code := run(t, "", "buckets", "-quiet", path); code != 0 || strings.Contains(out, "a/b") {
+		t.Fatalf("buckets = %q, exit status %d, want only top-level buckets", out, code)
+	}
+}
+
+// Nested buckets count as buckets, not as keys of their parent.
+func TestSummary(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k1=v1", "k2=v2"}, "z": nil})
+	for _, args := range [][]string{
+		{"create-bucket", path, "a/b/c"},
+		{"insert", path, "a/b", "k", "value"},
+		{"insert", path, "a/b/c", "k", "v"},
+	} {
+		if _, code := run(t, "", args...); code != 0 {
+			t.Fatalf("%q: exit status %d", args, code)
+		}
+	}
+	out, code := run(t, "", "summary", "-deep", "-bytes", "raw", path)
+	if code != 0 {
+		t.Fatalf("summary: exit status %d", code)
+	}
+	got := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		name, value, _ := strings.Cut(line, ":")
+		got[name] = strings.TrimSpace(value)
+	}
+	for name, want := range map[string]string{"Buckets": "4", "Keys": "4", "Value bytes": "10"} {
+		if got[name] != want {
+			t.Errorf("%s = %q, want %q in %q", name, got[name], want, out)
+		}
+	}
+}
+
+// Bulk loads with -no-sync still leave every pair in the file once the
+// command has closed the database.
+func TestNoSync(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&in, "k%05d,v%d\n", i, i)
+	}
+	src := tempDB(t, nil)
+	if out, code := run(t, in.String(), "load", "-no-sync", "-format", "csv", "-batch-size", "100", src, "b"); code != 0 || out != "loaded 5000 pairs\n" {
+		t.Fatalf("load -no-sync = %q, exit status %d", out, code)
+	}
+	if got := listKeys(t, src, "b"); len(got) != 5000 || got[4999] != "k04999" {
+		t.Fatalf("load -no-sync wrote %d keys", len(got))
+	}
+
+	export, code := run(t, "", "export", src)
+	if code != 0 {
+		t.Fatalf("export: exit status %d", code)
+	}
+	dst := tempDB(t, nil)
+	if _, code := run(t, export, "import", "-no-sync", "-batch-size", "100", dst); code != 0 {
+		t.Fatalf("import -no-sync: exit status %d", code)
+	}
+	if got, want := contents(t, dst), contents(t, src); !reflect.DeepEqual(got, want) {
+		t.Fatalf("import -no-sync: got %d lines, want %d", len(got), len(want))
+	}
+}
+
+func TestFirstLast(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"m=2", "a=1", "z=3"}, "empty": nil})
+	for _, tt := range []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"first", path, "b"}, "a\t1\n", 0},
+		{[]string{"last", path, "b"}, "z\t3\n", 0},
+		{[]string{"first", path, "empty"}, "", 0},
+		{[]string{"last", path, "empty"}, "", 0},
+		{[]string{"first", path, "missing"}, "", 1},
+	} {
+		if out, code := run(t, "", tt.args...); code != tt.code || out != tt.want {
+			t.Errorf("%q = %q, exit status %d, want %q, %d", tt.args, out, code, tt.want, tt.code)
+		}
+	}
+}
+
+// -progress reports on stderr while a scan runs, and only when asked.
+func TestProgress(t *testing.T) {
+	interval := progressInterval
+	t.Cleanup(func() { progressInterval = interval })
+	progressInterval = time.Millisecond
+
+	var stderr bytes.Buffer
+	cmd := &CommonCommand{Stderr: &stderr}
+	n := int64(42)
+	stop := cmd.startProgress(&n)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	if !strings.HasPrefix(stderr.String(), "progress: 42 keys, ") {
+		t.Fatalf("progress = %q", stderr.String())
+	}
+
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	for _, args := range [][]string{{"list", path, "b"}, {"dump", path}} {
+		m := newTestMain()
+		if err := m.Run(args...); err != nil {
+			t.Fatalf("%q: %s", args, err)
+		} else if m.Stderr.Len() != 0 {
+			t.Fatalf("%q wrote %q to stderr without -progress", args, m.Stderr.String())
+		}
+	}
+}
+
+// Several buckets are listed in sections; a missing one is skipped with a
+// warning unless -strict is set.
+func TestList_MultipleBuckets(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k1=v1"}, "b": {"k2=v2"}})
+	m := newTestMain()
+	if err := m.Run("list", "-quiet", path, "a", "missing", "b"); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(m.Stdout.String(), "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	if want := []string{"# a", "k1           v1", "", "# b", "k2           v2", ""}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("list = %q, want %q", lines, want)
+	}
+	if !strings.Contains(m.Stderr.String(), `warning: bucket "missing" not found`) {
+		t.Fatalf("stderr = %q, want a warning", m.Stderr.String())
+	}
+	if _, code := run(t, "", "list", "-strict", path, "a", "missing", "b"); code != 1 {
+		t.Fatalf("list -strict: exit status %d, want 1", code)
+	}
+}
+
+// -max-value cuts values to N bytes and says how many were left out.
+func TestList_MaxValue(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"big=" + strings.Repeat("x", 1000), "small=abc"}})
+	out, code := run(t, "", "list", "-values-only", "-max-value", "10", path, "b")
+	if want := strings.Repeat("x", 10) + "…(+990 more)\nabc\n"; code != 0 || out != want {
+		t.Fatalf("list -max-value 10 = %q, exit status %d, want %q", out, code, want)
+	}
+}
+
+// The completion scripts offer every command Main.Run knows.
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh"} {
+		out, code := run(t, "", "completion", shell)
+		if code != 0 {
+			t.Fatalf("completion %s: exit status %d", shell, code)
+		}
+		words := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(out, func(r rune) bool { return r != '-' && !unicode.IsLetter(r) }) {
+			words[word] = true
+		}
+		for _, name := range commandNames {
+			if !words[name] {
+				t.Errorf("completion %s doesn't offer %s", shell, name)
+			}
+		}
+		if !strings.Contains(out, "buckets -names-only") {
+			t.Errorf("completion %s doesn't complete bucket names", shell)
+		}
+	}
+	for _, name := range commandNames {
+		if err := newTestMain().Run(name, "-h"); err == ErrUnknownCommand {
+			t.Errorf("%s is offered for completion but isn't a command", name)
+		}
+	}
+	if _, code := run(t, "", "completion", "fish"); code == 0 {
+		t.Fatal("completion fish: expected an error")
+	}
+}
+
+// -touch creates a missing database for writing; without it a missing
+// file is an error and nothing is created.
+func TestTouch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.db")
+	if _, code := run(t, "", "insert", "-create-bucket", path, "b", "k", "v"); code != 1 {
+		t.Fatalf("insert without -touch: exit status %d, want 1", code)
+	} else if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("insert without -touch created %s", path)
+	}
+	if _, code := run(t, "", "insert", "-touch", "-create-bucket", path, "b", "k", "v"); code != 0 {
+		t.Fatalf("insert -touch: exit status %d", code)
+	}
+	if got, want := contents(t, path), []string{`bucket "b" seq 0`, `"b" "k" = "v"`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+// Nested buckets are marked in list, or left out with -no-buckets.
+func TestList_NestedBucket(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	if _, code := run(t, "", "create-bucket", path, "b/sub"); code != 0 {
+		t.Fatalf("create-bucket: exit status %d", code)
+	}
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"list", "-quiet", path, "b"}, []string{"k v", "sub [bucket]"}},
+		{[]string{"list", "-quiet", "-no-buckets", path, "b"}, []string{"k v"}},
+	} {
+		out, code := run(t, "", tt.args...)
+		if code != 0 {
+			t.Fatalf("%q: exit status %d", tt.args, code)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			got = append(got, strings.Join(strings.Fields(line), " "))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+// A tiny bench reports throughput for both phases and cleans up after
+// itself.
+func TestBench(t *testing.T) {
+	path := tempDB(t, map[string][]string{"x": {"k=v"}})
+	before := contents(t, path)
+	out, code := run(t, "", "bench", "-count", "10", "-no-sync", path)
+	if code != 0 {
+		t.Fatalf("bench: exit status %d", code)
+	}
+	for _, phase := range []string{"Write:", "Read:"} {
+		var line string
+		for _, l := range strings.Split(out, "\n") {
+			if strings.HasPrefix(l, phase) {
+				line = l
+			}
+		}
+		var n int
+		var elapsed string
+		var rate float64
+		if _, err := fmt.Sscanf(strings.TrimSpace(strings.TrimPrefix(line, phase)), "%d ops in %s (%g ops/sec", &n, &elapsed, &rate); err != nil {
+			t.Errorf("bench %s line %q: %s", phase, line, err)
+		} else if n != 10 || rate <= 0 {
+			t.Errorf("bench %s line %q: want 10 ops at a positive rate", phase, line)
+		}
+	}
+	if got := contents(t, path); !reflect.DeepEqual(got, before) {
+		t.Fatalf("bench left %q, want %q", got, before)
+	}
+}
+
+// -if-absent and -if-present write only when their condition holds and
+// otherwise exit with status 4.
+func TestInsert_Guards(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"-if-absent", "k", "v2"}, 4},
+		{[]string{"-if-absent", "new", "n"}, 0},
+		{[]string{"-if-present", "k", "v3"}, 0},
+		{[]string{"-if-present", "missing", "m"}, 4},
+	} {
+		args := append([]string{"insert", tt.args[0], path, "b"}, tt.args[1:]...)
+		if out, code := run(t, "", args...); code != tt.code || out != "" {
+			t.Errorf("%q = %q, exit status %d, want %d", args, out, code, tt.code)
+		}
+	}
+	if got, want := contents(t, path), []string{`bucket "b" seq 0`, `"b" "k" = "v3"`, `"b" "new" = "n"`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+// tail -follow prints the last pairs, then the pairs appended between
+// polls.
+func TestTail_Follow(t *testing.T) {
+	path := tempDB(t, map[string][]string{"log": {"1=a", "2=b", "3=c"}})
+	m := newTestMain()
+	cmd := newTailCommand(m.Main)
+	tick := make(chan time.Time)
+	cmd.tick = tick
+	done := make(chan error, 1)
+	go func() { done <- cmd.Run("-n", "2", "-follow", path, "log") }()
+
+	// As in TestWatch, each unbuffered tick waits for the previous poll.
+	tick <- time.Now()
+	for _, kv := range [][2]string{{"4", "d"}, {"5", "e"}} {
+		db, err := bolt.Open(path, 0600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("log")).Put([]byte(kv[0]), []byte(kv[1]))
+		}); err != nil {
+			t.Fatal(err)
+		}
+		_ = db.Close()
+		tick <- time.Now()
+		tick <- time.Now()
+	}
+	cmd.interrupt <- os.Interrupt
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Stdout.String(), "2\tb\n3\tc\n4\td\n5\te\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+// A -follow poll that can't take the lock within -timeout is skipped, and
+// a later poll picks up the changes.
+func TestTail_FollowLocked(t *testing.T) {
+	path := tempDB(t, map[string][]string{"log": {"1=a"}})
+	m := newTestMain()
+	cmd := newTailCommand(m.Main)
+	tick := make(chan time.Time)
+	cmd.tick = tick
+	done := make(chan error, 1)
+	go func() { done <- cmd.Run("-follow", "-interval", "1h", "-timeout", "50ms", path, "log") }()
+
+	tick <- time.Now()
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("log")).Put([]byte("2"), []byte("b"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tick <- time.Now()
+	tick <- time.Now()
+	if got, want := m.Stdout.String(), "1\ta\n"; got != want {
+		t.Fatalf("output while locked = %q, want %q", got, want)
+	}
+	_ = db.Close()
+	tick <- time.Now()
+	tick <- time.Now()
+	cmd.interrupt <- os.Interrupt
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Stdout.String(), "1\ta\n2\tb\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+// -values-only prints the values in key order, without nested buckets,
+// and honors the selection options.
+func TestList_ValuesOnly(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"c=3", "a=1", "b=2", "x:1=9"}})
+	if _, code := run(t, "", "create-bucket", path, "b/sub"); code != 0 {
+		t.Fatalf("create-bucket: exit status %d", code)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "1\n2\n3\n9\n"},
+		{[]string{"-limit", "2"}, "1\n2\n"},
+		{[]string{"-prefix", "x:"}, "9\n"},
+		{[]string{"-value-type", "hex"}, "31\n32\n33\n39\n"},
+	} {
+		args := append(append([]string{"list", "-values-only"}, tt.args...), path, "b")
+		if out, code := run(t, "", args...); code != 0 || out != tt.want {
+			t.Errorf("%q = %q, exit status %d, want %q", args, out, code, tt.want)
+		}
+	}
+}
+
+func TestList_SortNumeric(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"1=a", "2=b", "10=c", "20=d", "x=e"}})
+	if got, want := listKeys(t, path, "b"), []string{"1", "10", "2", "20", "x"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("byte order = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-sort", "numeric", path, "b"), []string{"1", "2", "10", "20", "x"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("numeric order = %q, want %q", got, want)
+	}
+	if _, code := run(t, "", "list", "-sort", "numeric", "-after", "2", path, "b"); code != 1 {
+		t.Fatalf("list -sort numeric -after: exit status %d, want 1", code)
+	}
+}
+
+func TestList_ExcludePrefix(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"group:1=g", "user:1=a", "user:2=b", "user:tmp1=c", "user:tmp2=d"}})
+	if got, want := listKeys(t, "-prefix", "user:", "-exclude-prefix", "user:tmp", path, "b"), []string{"user:1", "user:2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-exclude-prefix", "user:", path, "b"), []string{"group:1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+}
+
+// check-lock reports a database held open for writing with status 5.
+func TestCheckLock(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	if out, code := run(t, "", "check-lock", path); code != 0 || out != "" {
+		t.Fatalf("check-lock = %q, exit status %d, want 0", out, code)
+	}
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	m := newTestMain()
+	if code := m.report(m.Run("check-lock", "-timeout", "10ms", path)); code != 5 {
+		t.Fatalf("check-lock while locked: exit status %d, want 5", code)
+	} else if m.Stdout.Len() != 0 {
+		t.Fatalf("check-lock while locked printed %q", m.Stdout.String())
+	}
+}
+
+func TestSchema(t *testing.T) {
+	path := tempDB(t, map[string][]string{
+		"mixed": {`j={"a":1}`, "bin=\x00\xff\xfe", "t=hello"},
+		"json":  {`a={"id":1}`, `b=[1,2]`},
+	})
+	out, code := run(t, "", "schema", "-quiet", "-bytes", "raw", path)
+	if code != 0 {
+		t.Fatalf("schema: exit status %d", code)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"json 2 json 2 0 0 5 8 6",
+		"mixed 3 mixed 1 1 1 3 7 5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema = %q, want %q", got, want)
+	}
+}
+
+// -default stands in for a missing key; without it a missing key fails.
+func TestGet_Default(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v"}})
+	for _, tt := range []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"-default", "none", path, "b", "k"}, "v\n", 0},
+		{[]string{"-default", "none", path, "b", "missing"}, "none\n", 0},
+		{[]string{"-default", "", path, "b", "missing"}, "\n", 0},
+		{[]string{path, "b", "missing"}, "", 1},
+	} {
+		if out, code := run(t, "", append([]string{"get"}, tt.args...)...); code != tt.code || out != tt.want {
+			t.Errorf("get %q = %q, exit status %d, want %q, %d", tt.args, out, code, tt.want, tt.code)
+		}
+	}
+}
+
+// Bucket names and keys starting with "-" are given after "--".
+func TestDashDash(t *testing.T) {
+	path := tempDB(t, map[string][]string{"-x": {"k=v"}})
+	if _, code := run(t, "", "insert", path, "--", "-x", "-k", "-v"); code != 0 {
+		t.Fatalf("insert -- -x -k -v: exit status %d", code)
+	}
+	if out, code := run(t, "", "get", path, "--", "-x", "-k"); code != 0 || out != "-v\n" {
+		t.Fatalf("get -- -x -k = %q, exit status %d", out, code)
+	}
+	if got, want := listKeys(t, path, "--", "-x"), []string{"-k", "k"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("list -- -x = %q, want %q", got, want)
+	}
+
+	// Without "--", a name that is also a flag is taken as the flag.
+	path = tempDB(t, map[string][]string{"-quiet": {"k=v"}})
+	if _, code := run(t, "", "list", path, "-quiet"); code != 1 {
+		t.Fatalf("list -quiet: exit status %d, want 1", code)
+	}
+	if got, want := listKeys(t, path, "--", "-quiet"), []string{"k"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("list -- -quiet = %q, want %q", got, want)
+	}
+}
+
+// countingWriter counts the writes made to it.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+// list buffers its rows into few writes, and what it buffered is written
+// even when it fails part way through.
+func TestList_Buffered(t *testing.T) {
+	pairs := make([]string, 5000)
+	for i := range pairs {
+		pairs[i] = fmt.Sprintf("k%04d=v%d", i, i)
+	}
+	path := tempDB(t, map[string][]string{"a": pairs})
+
+	m := newTestMain()
+	w := &countingWriter{}
+	m.Main.Stdout = w
+	if err := m.Run("list", "-quiet", path, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := strings.Count(w.String(), "\n"); rows != len(pairs) {
+		t.Fatalf("list printed %d rows, want %d", rows, len(pairs))
+	} else if w.writes > rows/10 {
+		t.Fatalf("list made %d writes for %d rows", w.writes, rows)
+	}
+	if got := listKeys(t, "-offset", "4998", path, "a"); !reflect.DeepEqual(got, []string{"k4998", "k4999"}) {
+		t.Fatalf("last rows = %q", got)
+	}
+
+	m = newTestMain()
+	if err := m.Run("list", "-quiet", "-strict", path, "a", "missing"); err == nil {
+		t.Fatal("list -strict with a missing bucket: expected an error")
+	} else if rows := strings.Count(m.Stdout.String(), "\n"); rows != len(pairs)+1 {
+		t.Fatalf("list printed %d lines before failing, want %d", rows, len(pairs)+1)
+	}
+}
+
+// -strip-prefix shortens the keys shown, not the keys stored.
+func TestList_StripPrefix(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"app:users:1=a", "app:users:2=b", "other=x"}})
+	if got, want := listKeys(t, "-prefix", "app:users:", "-strip-prefix", "app:users:", path, "b"), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-strip-prefix", "app:users:", path, "b"), []string{"1", "2", "other"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	if _, code := run(t, "", "list", "-strict", "-strip-prefix", "app:users:", path, "b"); code != 1 {
+		t.Fatalf("list -strict -strip-prefix: exit status %d, want 1", code)
+	}
+	if got, want := listKeys(t, path, "b"), []string{"app:users:1", "app:users:2", "other"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("stored keys = %q, want %q", got, want)
+	}
+}
+
+// -decode gzip gunzips values, and shows others as stored with a warning.
+func TestDecodeGzip(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	if _, err := zw.Write([]byte(`{"hello":"world"}`)); err != nil {
+		t.Fatal(err)
+	} else if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := tempDB(t, map[string][]string{"b": {"plain=text", "z=" + zipped.String()}})
+
+	if out, code := run(t, "", "get", "-decode", "gzip", path, "b", "z"); code != 0 || out != "{\"hello\":\"world\"}\n" {
+		t.Fatalf("get -decode gzip = %q, exit status %d", out, code)
+	}
+	m := newTestMain()
+	if err := m.Run("list", "-values-only", "-decode", "gzip", path, "b"); err != nil {
+		t.Fatal(err)
+	} else if got, want := m.Stdout.String(), "text\n{\"hello\":\"world\"}\n"; got != want {
+		t.Fatalf("list -decode gzip = %q, want %q", got, want)
+	} else if !strings.Contains(m.Stderr.String(), "warning") || !strings.Contains(m.Stderr.String(), "plain") {
+		t.Fatalf("stderr = %q, want a warning about plain", m.Stderr.String())
+	}
+}
+
+// -verbose times the open and the scan on stderr, leaving stdout alone.
+func TestVerbose(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k1=v1", "k2=v2"}})
+	m := newTestMain()
+	if err := m.Run("list", "-verbose", "-quiet", path, "b"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(m.Stderr.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "open: ") || !strings.HasPrefix(lines[1], "scan: ") || !strings.HasSuffix(lines[1], ", 2 rows") {
+		t.Fatalf("stderr = %q, want open and scan timings", m.Stderr.String())
+	}
+	if strings.Count(m.Stdout.String(), "\n") != 2 {
+		t.Fatalf("stdout = %q, want only the rows", m.Stdout.String())
+	}
+}
+
+// replace writes the new value and deletes the other keys together, or
+// not at all.
+func TestReplace(t *testing.T) {
+	path := tempDB(t, map[string][]string{"users": {"alice=old"}, "cache": {"alice=stale", "bob=b"}})
+	if _, code := run(t, "", "replace", path, "users", "alice", "new", "-also-delete", "cache:alice", "-also-delete", "cache:nobody"); code != 0 {
+		t.Fatalf("replace: exit status %d", code)
+	}
+	want := []string{`bucket "cache" seq 0`, `"cache" "bob" = "b"`, `bucket "users" seq 0`, `"users" "alice" = "new"`}
+	if got := contents(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+
+	// A missing bucket fails the whole transaction.
+	for _, args := range [][]string{
+		{"replace", path, "users", "alice", "newer", "-also-delete", "cache:bob", "-also-delete", "missing:k"},
+		{"replace", path, "users", "alice", "newer", "-also-delete", "no-colon"},
+	} {
+		if _, code := run(t, "", args...); code != 1 {
+			t.Fatalf("%q: exit status %d, want 1", args, code)
+		}
+	}
+	if got := contents(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents after failures = %q, want %q", got, want)
+	}
+}
+
+// -max-depth stops the recursive commands at buckets nested too deeply.
+func TestMaxDepth(t *testing.T) {
+	path := tempDB(t, nil)
+	if _, code := run(t, "", "create-bucket", "-touch", path, "a/b/c/d"); code != 0 {
+		t.Fatalf("create-bucket: exit status %d", code)
+	}
+	for _, cmd := range [][]string{{"buckets", "-r"}, {"export"}, {"export", "-stream"}} {
+		args := append(append([]string{}, cmd...), "-max-depth", "2", path)
+		if err := newTestMain().Run(args...); !errors.Is(err, ErrMaxDepth) || !strings.Contains(err.Error(), "a/b/c/d") {
+			t.Errorf("%q: error %v, want %v for a/b/c/d", args, err, ErrMaxDepth)
+		}
+		for _, depth := range []string{"3", "0"} {
+			args := append(append([]string{}, cmd...), "-max-depth", depth, path)
+			if _, code := run(t, "", args...); code != 0 {
+				t.Errorf("%q: exit status %d", args, code)
+			}
+		}
+	}
+}
+
+// -json-since and -json-until select values by their timestamp field.
+func TestList_JSONTime(t *testing.T) {
+	path := tempDB(t, map[string][]string{"events": {
+		`1={"ts":"2024-01-01T00:00:00Z"}`,
+		`2={"ts":"2024-06-01T00:00:00Z"}`,
+		`3={"ts":"2024-12-01T00:00:00Z"}`,
+		`4=not json`,
+		`5={"at":"2024-06-01T00:00:00Z"}`,
+	}})
+	if got, want := listKeys(t, "-json-since", "2024-03-01T00:00:00Z", "-json-until", "2024-09-01T00:00:00Z", path, "events"), []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-json-since", "2024-06-01T00:00:00Z", path, "events"), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("-json-since keys = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-ts-field", "at", "-json-until", "2025-01-01T00:00:00Z", path, "events"), []string{"5"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("-ts-field keys = %q, want %q", got, want)
+	}
+	if _, code := run(t, "", "list", "-json-since", "yesterday", path, "events"); code != 1 {
+		t.Fatalf("list -json-since yesterday: exit status %d, want 1", code)
+	}
+}
+
+// Scanning buckets in parallel gives the same sizes, in the same order, as
+// scanning them one at a time.
+func TestBuckets_Parallel(t *testing.T) {
+	buckets := make(map[string][]string)
+	for i := 0; i < 20; i++ {
+		var pairs []string
+		for j := 0; j < i*10; j++ {
+			pairs = append(pairs, fmt.Sprintf("k%d=%s", j, strings.Repeat("v", j)))
+		}
+		buckets[fmt.Sprintf("b%02d", i)] = pairs
+	}
+	path := tempDB(t, buckets)
+	for _, format := range []string{"text", "json"} {
+		serial, code := run(t, "", "buckets", "-size", "-bytes", "raw", "-format", format, path)
+		if code != 0 {
+			t.Fatalf("buckets -size -format %s: exit status %d", format, code)
+		}
+		for _, n := range []string{"4", "32"} {
+			if out, code := run(t, "", "buckets", "-size", "-bytes", "raw", "-format", format, "-parallel", n, path); code != 0 || out != serial {
+				t.Errorf("buckets -parallel %s -format %s = %q, exit status %d, want %q", n, format, out, code, serial)
+			}
+		}
+	}
+}
+
+func TestCas(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"k=v1"}})
+	for _, tt := range []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{[]string{"k", "v1", "v2"}, 0, ""},
+		{[]string{"k", "v1", "v3"}, 4, "v2\n"},
+		{[]string{"new", "", "n"}, 0, ""},
+		{[]string{"new", "", "n2"}, 4, "n\n"},
+		{[]string{"missing", "x", "y"}, 4, "<null>\n"},
+	} {
+		m := newTestMain()
+		err := m.Run(append([]string{"cas", path, "b"}, tt.args...)...)
+		if code := exitCode(err); code != tt.code || m.Stderr.String() != tt.stderr {
+			t.Errorf("cas %q: exit status %d, stderr %q, want %d, %q", tt.args, code, m.Stderr.String(), tt.code, tt.stderr)
+		}
+	}
+	if got, want := contents(t, path), []string{`bucket "b" seq 0`, `"b" "k" = "v2"`, `"b" "new" = "n"`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+// -pretty and -format-value json-pretty indent JSON values and leave the
+// rest as stored.
+func TestPretty(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {`j={"a":1,"b":[1,2]}`, "t=plain {"}})
+	pretty := "{\n  \"a\": 1,\n  \"b\": [\n    1,\n    2\n  ]\n}\n"
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"get", "-pretty", path, "b", "j"}, pretty},
+		{[]string{"get", "-format-value", "json-pretty", path, "b", "j"}, pretty},
+		{[]string{"get", "-pretty", path, "b", "t"}, "plain {\n"},
+		{[]string{"list", "-values-only", "-pretty", path, "b"}, pretty + "plain {\n"},
+	} {
+		if out, code := run(t, "", tt.args...); code != 0 || out != tt.want {
+			t.Errorf("%q = %q, exit status %d, want %q", tt.args, out, code, tt.want)
+		}
+	}
+}
+
+// After set-sequence N the next sequence is N+1, and export and import
+// carry the sequence over.
+func TestSetSequence(t *testing.T) {
+	path := tempDB(t, map[string][]string{"log": nil})
+	if _, code := run(t, "", "set-sequence", path, "log", "41"); code != 0 {
+		t.Fatalf("set-sequence: exit status %d", code)
+	}
+	if out, code := run(t, "", "insert", "-seq", path, "log", "v"); code != 0 || out != "42\n" {
+		t.Fatalf("insert -seq = %q, exit status %d, want 42", out, code)
+	}
+	if _, code := run(t, "", "set-sequence", path, "missing", "1"); code != 1 {
+		t.Fatalf("set-sequence on a missing bucket: exit status %d, want 1", code)
+	}
+
+	export, _ := run(t, "", "export", path)
+	dst := tempDB(t, nil)
+	if _, code := run(t, export, "import", dst); code != 0 {
+		t.Fatalf("import: exit status %d", code)
+	}
+	if got := contents(t, dst); len(got) == 0 || got[0] != `bucket "log" seq 42` {
+		t.Fatalf("imported contents = %q, want sequence 42", got)
+	}
+}
+
+// A key can't be used as a bucket in a path, nor a nested bucket as a
+// key, and the error names the offending name.
+func TestInsert_NotABucket(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k=v"}})
+	if _, code := run(t, "", "create-bucket", path, "a/sub"); code != 0 {
+		t.Fatalf("create-bucket: exit status %d", code)
+	}
+	for _, tt := range []struct {
+		args []string
+		err  error
+		name string
+	}{
+		{[]string{"insert", path, "a/k/c", "x", "y"}, ErrNotABucket, "a/k"},
+		{[]string{"insert", "-create-bucket", path, "a/k/c", "x", "y"}, ErrNotABucket, "a/k"},
+		{[]string{"insert", path, "a", "sub", "v"}, nil, `"sub"`},
+	} {
+		err := newTestMain().Run(tt.args...)
+		if err == nil || (tt.err != nil && !errors.Is(err, tt.err)) || !strings.Contains(err.Error(), tt.name) {
+			t.Errorf("%q: error %v, want one naming %s", tt.args, err, tt.name)
+		}
+	}
+	if got, want := contents(t, path), []string{`bucket "a" seq 0`, `"a" "k" = "v"`, `bucket "a/sub" seq 0`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+// Salvaging a healthy database recovers all of it.
+func TestSalvage(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k1=v1", "k2=v2"}, "b": nil})
+	if _, code := run(t, "", "insert", "-create-bucket", path, "a/c", "k3", "v3"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	dst := filepath.Join(t.TempDir(), "copy.db")
+	out, code := run(t, "", "salvage", path, dst)
+	if code != 0 {
+		t.Fatalf("salvage: exit status %d", code)
+	} else if want := "recovered 3 keys, skipped 0 buckets\n"; out != want {
+		t.Fatalf("salvage = %q, want %q", out, want)
+	}
+	if got, want := contents(t, dst), contents(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("copy = %q, want %q", got, want)
+	}
+	if _, code := run(t, "", "salvage", path, dst); code != 1 {
+		t.Fatalf("salvage to existing DST: exit status %d, want 1", code)
+	}
+}
+
+func TestInsert_Expand(t *testing.T) {
+	t.Setenv("BOLT_TEST_SECRET", "s3cret")
+	path := tempDB(t, map[string][]string{"b": nil})
+	for _, tt := range []struct {
+		flag  string
+		key   string
+		value string
+		code  int
+	}{
+		{"-expand", "defined", "pw=${BOLT_TEST_SECRET}", 0},
+		{"-expand=false", "literal", "pw=${BOLT_TEST_SECRET}", 0},
+		{"-expand", "lenient", "pw=${BOLT_TEST_UNDEFINED}", 0},
+		{"-strict-env", "strict", "pw=${BOLT_TEST_UNDEFINED}", 1},
+	} {
+		args := []string{"insert", tt.flag, path, "b", tt.key, tt.value}
+		if _, code := run(t, "", args...); code != tt.code {
+			t.Errorf("%q: exit status %d, want %d", args, code, tt.code)
+		}
+	}
+	if got, want := contents(t, path), []string{
+		`bucket "b" seq 0`,
+		`"b" "defined" = "pw=s3cret"`,
+		`"b" "lenient" = "pw="`,
+		`"b" "literal" = "pw=${BOLT_TEST_SECRET}"`,
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+}
+
+func TestFormatKeyValue(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	if _, code := run(t, "", "insert", "-key-type", "uint64be", path, "b", "7", `{"a":1,"b":[2]}`); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	pretty := "{\n  \"a\": 1,\n  \"b\": [\n    2\n  ]\n}\n"
+	if out, code := run(t, "", "list", "-quiet", "-format-key", "uint64be", "-format-value", "json-pretty", path, "b"); code != 0 {
+		t.Fatalf("list: exit status %d", code)
+	} else if !strings.HasPrefix(out, "7 ") || !strings.HasSuffix(out, " "+pretty) {
+		t.Fatalf("list = %q, want key 7 and an indented value", out)
+	}
+	if out, code := run(t, "", "get", "-format-key", "uint64be", "-format-value", "json-pretty", path, "b", "7"); code != 0 {
+		t.Fatalf("get: exit status %d", code)
+	} else if out != pretty {
+		t.Fatalf("get = %q, want %q", out, pretty)
+	}
+	if err := newTestMain().Run("list", "-format-key", "uint64be", "-key-type", "hex", path, "b"); err == nil || !strings.Contains(err.Error(), "-format-key") {
+		t.Fatalf("list -format-key -key-type: error %v, want one naming -format-key", err)
+	}
+}
+
+func TestBatch_File(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {"old=x"}})
+	script := filepath.Join(t.TempDir(), "script.txt")
+	if err := os.WriteFile(script, []byte("mkbucket c\nput c k hello world\ndel b old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := run(t, "ignored", "batch", "-f", script, path); code != 0 || out != "applied 3 operations\n" {
+		t.Fatalf("batch -f = %q, exit status %d", out, code)
+	}
+	if got, want := contents(t, path), []string{`bucket "b" seq 0`, `bucket "c" seq 0`, `"c" "k" = "hello world"`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("contents = %q, want %q", got, want)
+	}
+
+	// With -batch-size, the batches before a failing line stay applied.
+	if err := os.WriteFile(script, []byte("put b k1 v\nput b k2 v\nput missing k v\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := run(t, "", "batch", "-f", script, "-batch-size", "2", path); code == 0 || out != "applied 2 operations\n" {
+		t.Fatalf("batch -batch-size 2 = %q, exit status %d, want a failure after 2 operations", out, code)
+	}
+	if got, want := listKeys(t, path, "b"), []string{"k1", "k2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+}
+
+// A lock timeout tells the user who probably holds the file.
+func TestOpenDB_LockAdvice(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": nil})
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	err = newTestMain().Run("list", "-timeout", "100ms", path, "b")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("list: error %v, want %v", err, ErrLocked)
+	}
+	for _, s := range []string{path, "Another process", "hung process"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q doesn't mention %q", err, s)
+		}
+	}
+}
+
+func TestList_Select(t *testing.T) {
+	path := tempDB(t, map[string][]string{"b": {
+		`a={"user":{"name":"ann"},"tags":["x","y"]}`,
+		`b={"user":{"id":2}}`,
+		`c=plain`,
+		`d={"user":{"name":42}}`,
+	}})
+	fields := func(args ...string) [][]string {
+		t.Helper()
+		out, code := run(t, "", append([]string{"list", "-quiet"}, args...)...)
+		if code != 0 {
+			t.Fatalf("list %q: exit status %d", args, code)
+		}
+		var rows [][]string
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			rows = append(rows, strings.Fields(line))
+		}
+		return rows
+	}
+	if got, want := fields("-select", "user.name", path, "b"), [][]string{{"a", "ann"}, {"b"}, {"c"}, {"d", "42"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("-select user.name = %q, want %q", got, want)
+	}
+	if got, want := fields("-select", "tags.1", path, "b"), [][]string{{"a", "y"}, {"b"}, {"c"}, {"d"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("-select tags.1 = %q, want %q", got, want)
+	}
+	if got, want := listKeys(t, "-select", "user.name", "-skip-missing", path, "b"), []string{"a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("-skip-missing keys = %q, want %q", got, want)
+	}
+}
+
+// The checksum depends only on the contents, not on how they were written.
+func TestChecksum(t *testing.T) {
+	a := tempDB(t, map[string][]string{"x": {"k1=v1", "k2=v2"}, "y": {"k=v"}})
+	b := tempDB(t, map[string][]string{"y": {"k=v", "gone=soon"}, "x": {"k2=v2"}})
+	for _, args := range [][]string{
+		{"insert", b, "x", "k1", "v1"},
+		{"delete", b, "y", "gone"},
+	} {
+		if _, code := run(t, "", args...); code != 0 {
+			t.Fatalf("%q: exit status %d", args, code)
+		}
+	}
+	checksum := func(args ...string) string {
+		t.Helper()
+		out, code := run(t, "", append([]string{"checksum"}, args...)...)
+		if code != 0 {
+			t.Fatalf("checksum %q: exit status %d", args, code)
+		} else if len(out) != 65 {
+			t.Fatalf("checksum %q = %q, want a SHA-256 hex digest", args, out)
+		}
+		return out
+	}
+	if sa, sb := checksum(a), checksum(b); sa != sb {
+		t.Fatalf("checksums differ: %q, %q", sa, sb)
+	}
+	if sa, sb := checksum(a, "x"), checksum(b, "x"); sa != sb {
+		t.Fatalf("bucket checksums differ: %q, %q", sa, sb)
+	}
+	if checksum(a, "x") == checksum(a) {
+		t.Fatal("bucket checksum equals the database checksum")
+	}
+	if _, code := run(t, "", "insert", b, "y", "k", "v2"); code != 0 {
+		t.Fatalf("insert: exit status %d", code)
+	}
+	if checksum(a) == checksum(b) {
+		t.Fatal("checksums match after a change")
+	}
+}
+
+func TestBuckets_NonEmpty(t *testing.T) {
+	path := tempDB(t, map[string][]string{"a": {"k=v"}, "b": nil, "c": {"k=v"}})
+	if _, code := run(t, "", "create-bucket", path, "a/empty"); code != 0 {
+		t.Fatalf("create-bucket: exit status %d", code)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"buckets", "-names-only", path}, "a\nb\nc\n"},
+		{[]string{"buckets", "-names-only", "-non-empty", path}, "a\nc\n"},
+		{[]string{"buckets", "-names-only", "-non-empty", "-r", path}, "a\nc\n"},
+	} {
+		if out, code := run(t, "", tt.args...); code != 0 || out != tt.want {
+			t.Errorf("%q = %q, exit status %d, want %q", tt.args, out, code, tt.want)
+		}
+	}
+	empty := tempDB(t, map[string][]string{"b": nil})
+	if _, code := run(t, "", "buckets", "-names-only", "-non-empty", empty); code != 3 {
+		t.Fatalf("buckets -non-empty with only empty buckets: exit status %d, want 3", code)
+	}
+}
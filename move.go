@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type MoveCommand struct {
+	CommonCommand
+}
+
+func newMoveCommand(m *Main) *MoveCommand {
+	return &MoveCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *MoveCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	newKey := fs.String("new-key", "", "")
+	keyType := fs.String("key-type", typeString, "")
+	fs.Var(encodingFlag{keyType}, "input-encoding", "")
+	dryRun := fs.Bool("dry-run", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := exclusive(fs, "input-encoding", "key-type"); err != nil {
+		return err
+	} else if err := checkType(*keyType); err != nil {
+		return err
+	}
+
+	cmd.maxArgs = 3
+
+	src := cmd.arg(fs, 0)
+	if src == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+	if key == "" {
+		return ErrKeyRequired
+	}
+	dst := cmd.arg(fs, 2)
+	if dst == "" {
+		return ErrBucketRequired
+	}
+	from, err := encodeKey(*keyType, key)
+	if err != nil {
+		return err
+	}
+	var to []byte
+	if *newKey != "" {
+		if to, err = encodeKey(*keyType, *newKey); err != nil {
+			return err
+		}
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), *dryRun)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if *dryRun {
+		dstKey := key
+		if *newKey != "" {
+			dstKey = *newKey
+		}
+		return db.View(func(tx *bolt.Tx) error {
+			bucket, err := boltview.LookupBucket(tx, src)
+			if err != nil {
+				return err
+			} else if bucket.Get(from) == nil {
+				return ErrKeyNotFound
+			} else if src == dst && key == dstKey {
+				return nil
+			}
+			fmt.Fprintf(cmd.Stdout, "- %s\t%s\n", src, key)
+			fmt.Fprintf(cmd.Stdout, "+ %s\t%s\n", dst, dstKey)
+			return nil
+		})
+	}
+
+	return boltview.Move(db, src, from, dst, to)
+}
+
+func (cmd *MoveCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt move [-new-key NAME] [-key-type TYPE | -input-encoding ENCODING]
+                 [-dry-run] PATH SRC_BUCKET KEY DST_BUCKET
+
+Move moves a key-value pair from SRC_BUCKET to DST_BUCKET in a single
+transaction, creating DST_BUCKET if needed. Either both the write and the
+delete happen or neither does.
+
+Additional options include:
+
+	-new-key NAME
+		Store the pair under NAME in DST_BUCKET instead of KEY.
+	-key-type TYPE
+		Encode KEY and NAME as TYPE, as in insert.
+	-input-encoding ENCODING
+		Decode KEY and NAME as raw, hex or base64.
+	-dry-run
+		Print the key that would be removed from SRC_BUCKET and
+		added to DST_BUCKET, without modifying the database.
+`, "\n")
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type ReplaceCommand struct {
+	CommonCommand
+}
+
+func newReplaceCommand(m *Main) *ReplaceCommand {
+	return &ReplaceCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// keyRefs is a repeatable flag of "bucket:key" references.
+type keyRefs [][2]string
+
+func (r *keyRefs) String() string { return fmt.Sprint(*r) }
+
+func (r *keyRefs) Set(s string) error {
+	i := strings.Index(s, ":")
+	if i <= 0 || i == len(s)-1 {
+		return errors.New("must be BUCKET:KEY")
+	}
+	*r = append(*r, [2]string{s[:i], s[i+1:]})
+	return nil
+}
+
+// Run executes the command.
+func (cmd *ReplaceCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	var alsoDelete keyRefs
+	fs.Var(&alsoDelete, "also-delete", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 3
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+	if key == "" {
+		return ErrKeyRequired
+	}
+	value := cmd.arg(fs, 2)
+	if value == "" {
+		return ErrValueRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	// Put the value and delete the other keys in one transaction so a
+	// missing bucket rolls all of it back.
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := boltview.LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(key), []byte(value)); err != nil {
+			return err
+		} else if err := boltview.ClearExpiry(tx, bucketName, []byte(key)); err != nil {
+			return err
+		}
+		for _, ref := range alsoDelete {
+			other, err := boltview.LookupBucket(tx, ref[0])
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, ref[0])
+			}
+			if err := other.Delete([]byte(ref[1])); err != nil {
+				return err
+			} else if err := boltview.ClearExpiry(tx, ref[0], []byte(ref[1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (cmd *ReplaceCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt replace [-also-delete BUCKET:KEY]... PATH BUCKET_NAME KEY VALUE
+
+Replace sets KEY to VALUE in the bucket and, in the same transaction,
+deletes every key given with -also-delete. Either all of the changes are
+made or none are, e.g. to update a record and drop a stale cached copy:
+
+	bolt replace my.db users alice '{"age":31}' -also-delete cache:alice
+
+Additional options include:
+
+	-also-delete BUCKET:KEY
+		Delete KEY from BUCKET. May be repeated. Deleting a missing
+		key is not an error, but a missing bucket is.
+`, "\n")
+}
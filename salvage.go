@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+type SalvageCommand struct {
+	CommonCommand
+}
+
+func newSalvageCommand(m *Main) *SalvageCommand {
+	return &SalvageCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// salvaged is a bucket (with a nil key) or key-value pair read from a
+// damaged database, along with the path of buckets containing it.
+type salvaged struct {
+	path       [][]byte
+	key, value []byte
+}
+
+// Run executes the command.
+func (cmd *SalvageCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	// Bolt reads pages straight from the memory map, so a corrupt page
+	// reference faults. Turn faults into panics that safely can recover.
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+
+	// The destination must be a new file so nothing is overwritten.
+	dstPath := cmd.arg(fs, 0)
+	if dstPath == "" {
+		return ErrPathRequired
+	} else if _, err := os.Stat(dstPath); err == nil {
+		return fmt.Errorf("%s already exists", dstPath)
+	}
+
+	// Open databases.
+	var src *bolt.DB
+	if err := cmd.safely(func() (err error) {
+		src, err = cmd.openDB(cmd.path(fs), true)
+		return err
+	}); err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := bolt.Open(dstPath, 0666, cmd.options(false))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dst.Close() }()
+
+	// An interrupt stops the copy between buckets and removes DST, which
+	// would otherwise pass for a complete copy.
+	ctx, stop := notifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Copy one top-level bucket at a time, so that only a single bucket's
+	// worth of data is held in memory.
+	var names [][]byte
+	if err := cmd.safely(func() error {
+		return src.View(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				names = append(names, append([]byte(nil), name...))
+				return nil
+			})
+		})
+	}); err != nil {
+		fmt.Fprintf(cmd.Stderr, "warning: bucket list is incomplete: %s\n", err)
+	}
+
+	var keyN, skipped int
+	for _, name := range names {
+		if ctx.Err() != nil {
+			_ = dst.Close()
+			_ = os.Remove(dstPath)
+			return ErrInterrupted
+		}
+		var items []salvaged
+		_ = src.View(func(tx *bolt.Tx) error {
+			skipped += cmd.walk(&items, [][]byte{name}, tx.Bucket(name))
+			return nil
+		})
+		n, err := writeSalvaged(dst, items)
+		if err != nil {
+			return err
+		}
+		keyN += n
+	}
+
+	fmt.Fprintf(cmd.Stdout, "recovered %d keys, skipped %d buckets\n", keyN, skipped)
+	return nil
+}
+
+// safely calls fn, turning a panic caused by a damaged page into an error.
+func (cmd *SalvageCommand) safely(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return fn()
+}
+
+// walk appends the bucket at path and everything in it to items. A bucket
+// that can't be read to the end is reported and counted as skipped, but
+// the pairs read before the failure are kept. It returns the number of
+// buckets skipped.
+func (cmd *SalvageCommand) walk(items *[]salvaged, path [][]byte, bucket *bolt.Bucket) (skipped int) {
+	err := cmd.safely(func() error {
+		if bucket == nil {
+			return errors.New("unreadable bucket")
+		}
+		*items = append(*items, salvaged{path: path})
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			if v == nil {
+				child := append(append([][]byte(nil), path...), append([]byte(nil), k...))
+				skipped += cmd.walk(items, child, bucket.Bucket(k))
+				continue
+			}
+			*items = append(*items, salvaged{
+				path:  path,
+				key:   append([]byte(nil), k...),
+				value: append([]byte{}, v...),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "warning: skipping rest of bucket %s: %s\n", bytes2path(path), err)
+		skipped++
+	}
+	return skipped
+}
+
+// writeSalvaged stores items in db in one transaction and returns the
+// number of keys written.
+func writeSalvaged(db *bolt.DB, items []salvaged) (int, error) {
+	n := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, item := range items {
+			bucket, err := tx.CreateBucketIfNotExists(item.path[0])
+			for _, name := range item.path[1:] {
+				if err != nil {
+					break
+				}
+				bucket, err = bucket.CreateBucketIfNotExists(name)
+			}
+			if err != nil {
+				return err
+			}
+			if item.key == nil {
+				continue
+			}
+			if err := bucket.Put(item.key, item.value); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// bytes2path joins a bucket path for display, e.g. "parent/child".
+func bytes2path(path [][]byte) string {
+	names := make([]string, len(path))
+	for i, name := range path {
+		names[i] = string(name)
+	}
+	return strings.Join(names, "/")
+}
+
+func (cmd *SalvageCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt salvage SRC DST
+
+Salvage copies every bucket and key-value pair it can still read from a
+damaged database SRC into a new database DST, which must not exist yet.
+A bucket that fails part way through is reported on stderr and the rest
+of it is skipped, but the pairs read before the failure are kept. It
+prints the number of keys recovered and buckets skipped.
+
+Ctrl-C stops the copy after the current bucket, removes DST and exits with
+status 130.
+
+This is best effort: it can't recover data from pages bolt cannot reach.
+Check the copy before relying on it.
+`, "\n")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type SchemaCommand struct {
+	CommonCommand
+}
+
+func newSchemaCommand(m *Main) *SchemaCommand {
+	return &SchemaCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// valueShape summarizes the values sampled from a bucket.
+type valueShape struct {
+	name               string
+	n                  int
+	json, text, binary int
+	min, max, total    int
+}
+
+// add classifies v and updates the counts and sizes.
+func (s *valueShape) add(v []byte) {
+	if s.n == 0 || len(v) < s.min {
+		s.min = len(v)
+	}
+	if len(v) > s.max {
+		s.max = len(v)
+	}
+	s.n++
+	s.total += len(v)
+
+	// Only objects and arrays count as JSON; a bare number or string is
+	// just as likely to be plain text.
+	switch t := strings.TrimSpace(string(v)); {
+	case (strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[")) && json.Valid(v):
+		s.json++
+	case utf8.Valid(v):
+		s.text++
+	default:
+		s.binary++
+	}
+}
+
+// Run executes the command.
+func (cmd *SchemaCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addTableFlags(fs)
+	cmd.addSizeFlags(fs)
+	help := fs.Bool("h", false, "")
+	sample := fs.Int("sample", 100, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	bucketName := cmd.arg(fs, 0)
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	// Sample the first values of each bucket, skipping nested buckets.
+	var shapes []*valueShape
+	err = db.View(func(tx *bolt.Tx) error {
+		sampleBucket := func(name []byte, bucket *bolt.Bucket) error {
+			shape := &valueShape{name: string(name)}
+			cursor := bucket.Cursor()
+			for k, v := cursor.First(); k != nil && (*sample <= 0 || shape.n < *sample); k, v = cursor.Next() {
+				if v != nil {
+					shape.add(v)
+				}
+			}
+			shapes = append(shapes, shape)
+			return nil
+		}
+		if bucketName == "" {
+			return tx.ForEach(sampleBucket)
+		}
+		bucket, err := boltview.LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		return sampleBucket([]byte(bucketName), bucket)
+	})
+	if err != nil {
+		return err
+	}
+
+	width := 8
+	for _, s := range shapes {
+		if len(s.name) > width {
+			width = len(s.name)
+		}
+	}
+
+	// Write header.
+	cmd.header(
+		fmt.Sprintf("%-*s SAMPLED  KIND     JSON     TEXT     BINARY   MIN      MAX      AVG", width, "NAME"),
+		strings.Repeat("=", width)+strings.Repeat(" ========", 8),
+	)
+
+	for _, s := range shapes {
+		avg := 0
+		if s.n > 0 {
+			avg = s.total / s.n
+		}
+		fmt.Fprintf(cmd.Stdout, "%-*s %-8d %-8s %-8d %-8d %-8d %-8s %-8s %s\n",
+			width, s.name, s.n, s.kind(), s.json, s.text, s.binary,
+			cmd.formatBytes(int64(s.min)), cmd.formatBytes(int64(s.max)), cmd.formatBytes(int64(avg)))
+	}
+	return nil
+}
+
+// kind names the single kind of every sampled value, or "mixed".
+func (s *valueShape) kind() string {
+	switch s.n {
+	case 0:
+		return "empty"
+	case s.json:
+		return "json"
+	case s.text:
+		return "text"
+	case s.binary:
+		return "binary"
+	}
+	return "mixed"
+}
+
+func (cmd *SchemaCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt schema [-sample N] [-quiet] PATH [BUCKET_NAME]
+
+Schema samples the first values of every top-level bucket, or only of
+BUCKET_NAME, and guesses what they hold. Each value counts as JSON (an
+object or array), text (valid UTF-8) or binary. KIND names the kind shared
+by every sampled value, or "mixed". MIN, MAX and AVG are value sizes; see
+-bytes in "bolt help". Nested buckets are not sampled.
+
+This is a heuristic: it only looks at the first keys of each bucket.
+
+Additional options include:
+
+	-sample N
+		Sample up to N values per bucket (default 100). Zero samples
+		every value.
+	-quiet
+		Omit the header lines and print only the data rows.
+`, "\n")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type SetManyCommand struct {
+	CommonCommand
+}
+
+func newSetManyCommand(m *Main) *SetManyCommand {
+	return &SetManyCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *SetManyCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	stats := fs.Bool("stats", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Read the whole object before opening the database so that malformed
+	// input never leaves a partial write behind.
+	pairs, err := readJSONPairs(cmd.Stdin)
+	if err != nil {
+		return err
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := boltview.PutMany(db, bucketName, pairs); err != nil {
+		return err
+	}
+	if *stats {
+		cmd.printTxStats(db)
+	}
+	return nil
+}
+
+// readJSONPairs decodes a flat JSON object into key-value pairs sorted by
+// key. Strings are stored unquoted and numbers and booleans keep their
+// textual representation.
+func readJSONPairs(r io.Reader) ([]boltview.Pair, error) {
+	var obj map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("invalid json: %s", err)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]boltview.Pair, 0, len(keys))
+	for _, k := range keys {
+		if k == "" {
+			return nil, ErrKeyRequired
+		}
+
+		raw := obj[k]
+		var value []byte
+		switch raw[0] {
+		case '"':
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			value = []byte(s)
+		case '{', '[', 'n':
+			return nil, fmt.Errorf("value for key %q is not a string, number or boolean", k)
+		default:
+			value = raw
+		}
+		pairs = append(pairs, boltview.Pair{Key: []byte(k), Value: value})
+	}
+	return pairs, nil
+}
+
+func (cmd *SetManyCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt set-many [-stats] PATH BUCKET_NAME < FILE.json
+
+Set-many reads a flat JSON object from stdin and stores each member as a
+key-value pair in the bucket, creating the bucket if needed. All pairs are
+written in a single transaction. String values are stored without quotes;
+numbers and booleans are stored as their JSON text, e.g. 1.50 or true.
+
+Additional options include:
+
+	-stats
+		Print the write statistics of the transaction (pages
+		allocated, splits, spills, writes) to stderr.
+`, "\n")
+}
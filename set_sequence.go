@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type SetSequenceCommand struct {
+	CommonCommand
+}
+
+func newSetSequenceCommand(m *Main) *SetSequenceCommand {
+	return &SetSequenceCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *SetSequenceCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 2
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	if cmd.arg(fs, 1) == "" {
+		return ErrValueRequired
+	}
+	n, err := strconv.ParseUint(cmd.arg(fs, 1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid sequence: %s", err)
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	return boltview.SetSequence(db, bucketName, n)
+}
+
+func (cmd *SetSequenceCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt set-sequence PATH BUCKET_NAME N
+
+Set-sequence sets the bucket's sequence counter to N, so the next
+"insert -seq" into the bucket uses key N+1. Dump uses it to restore each
+bucket's sequence.
+`, "\n")
+}
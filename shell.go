@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type ShellCommand struct {
+	CommonCommand
+
+	db *bolt.DB
+
+	// cwd is the path of the current bucket, empty at the top level.
+	cwd []string
+
+	// history holds every line entered so far.
+	history []string
+}
+
+func newShellCommand(m *Main) *ShellCommand {
+	return &ShellCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// errExit is returned by a shell command to end the session.
+var errExit = errors.New("exit")
+
+// errShellReadOnly is returned by put and del without -rw.
+var errShellReadOnly = errors.New("the database is open read-only, start the shell with -rw to modify it")
+
+// Run executes the command.
+func (cmd *ShellCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addReadFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	// Open database. It is read-only unless -rw is given, so exploring
+	// doesn't block the application that owns it.
+	db, err := cmd.openDB(cmd.path(fs), !cmd.rw)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+	cmd.db = db
+
+	scanner := bufio.NewScanner(cmd.Stdin)
+	for {
+		fmt.Fprintf(cmd.Stdout, "bolt:/%s> ", strings.Join(cmd.cwd, "/"))
+		if !scanner.Scan() {
+			fmt.Fprintln(cmd.Stdout)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// "!!" and "!N" repeat an earlier line and are replaced by it in
+		// the history.
+		if strings.HasPrefix(line, "!") {
+			if line, err = cmd.recall(line); err != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+				continue
+			}
+			fmt.Fprintln(cmd.Stdout, line)
+		}
+		cmd.history = append(cmd.history, line)
+
+		if err := cmd.exec(line); err == errExit {
+			return nil
+		} else if err != nil {
+			fmt.Fprintln(cmd.Stderr, err)
+		}
+	}
+}
+
+// recall returns the history line referred to by "!!" or "!N".
+func (cmd *ShellCommand) recall(line string) (string, error) {
+	n := len(cmd.history)
+	if line != "!!" {
+		var err error
+		if n, err = strconv.Atoi(line[1:]); err != nil {
+			return "", fmt.Errorf("invalid history reference %q", line)
+		}
+	}
+	if n < 1 || n > len(cmd.history) {
+		return "", fmt.Errorf("%s: no such history entry", line)
+	}
+	return cmd.history[n-1], nil
+}
+
+// exec runs a single line of input.
+func (cmd *ShellCommand) exec(line string) error {
+	name, rest := splitField(line)
+	switch name {
+	case "ls":
+		return cmd.ls(rest)
+	case "cd":
+		return cmd.cd(rest)
+	case "get":
+		return cmd.get(rest)
+	case "put":
+		// The value is the rest of the line so it may contain spaces.
+		key, value := splitField(rest)
+		return cmd.put(key, value)
+	case "del":
+		return cmd.del(rest)
+	case "history":
+		for i, h := range cmd.history {
+			fmt.Fprintf(cmd.Stdout, "%5d  %s\n", i+1, h)
+		}
+		return nil
+	case "help":
+		fmt.Fprint(cmd.Stdout, shellHelp)
+		return nil
+	case "exit", "quit":
+		return errExit
+	}
+	return fmt.Errorf("unknown command %q, try help", name)
+}
+
+// resolve returns the path of the bucket named by dir relative to the
+// current bucket. dir may be absolute if it starts with "/", and may use
+// ".." for the parent. A segment is only split on "/" if no bucket has the
+// whole name, as with the nested paths taken by the other commands.
+func (cmd *ShellCommand) resolve(tx *bolt.Tx, dir string) ([]string, error) {
+	path := append([]string{}, cmd.cwd...)
+	if strings.HasPrefix(dir, "/") {
+		path, dir = nil, strings.TrimLeft(dir, "/")
+	}
+	if dir == "" {
+		return path, nil
+	} else if b, _ := cmd.bucket(tx, append(path, dir)); b != nil {
+		return append(path, dir), nil
+	}
+
+	for _, segment := range strings.Split(dir, "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			continue
+		}
+		path = append(path, segment)
+		if _, err := cmd.bucket(tx, path); err != nil {
+			return nil, err
+		}
+	}
+	return path, nil
+}
+
+// bucket returns the bucket at path, or nil for the top level.
+func (cmd *ShellCommand) bucket(tx *bolt.Tx, path []string) (*bolt.Bucket, error) {
+	var b *bolt.Bucket
+	for i, name := range path {
+		if i == 0 {
+			b = tx.Bucket([]byte(name))
+		} else {
+			b = b.Bucket([]byte(name))
+		}
+		if b == nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(path[:i+1], "/"), ErrBucketNotFound)
+		}
+	}
+	return b, nil
+}
+
+// current returns the current bucket, which is an error at the top level
+// where there are no keys.
+func (cmd *ShellCommand) current(tx *bolt.Tx) (*bolt.Bucket, error) {
+	if len(cmd.cwd) == 0 {
+		return nil, errors.New("not in a bucket, cd into one first")
+	}
+	return cmd.bucket(tx, cmd.cwd)
+}
+
+// ls prints the keys of the bucket named by dir, or of the current bucket.
+// Nested buckets end in "/".
+func (cmd *ShellCommand) ls(dir string) error {
+	return cmd.db.View(func(tx *bolt.Tx) error {
+		path, err := cmd.resolve(tx, dir)
+		if err != nil {
+			return err
+		}
+		b, err := cmd.bucket(tx, path)
+		if err != nil {
+			return err
+		} else if b == nil {
+			return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				fmt.Fprintf(cmd.Stdout, "%s/\n", name)
+				return nil
+			})
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				fmt.Fprintf(cmd.Stdout, "%s/\n", k)
+			} else {
+				fmt.Fprintf(cmd.Stdout, "%s\n", k)
+			}
+			return nil
+		})
+	})
+}
+
+// cd changes the current bucket. Without an argument it returns to the top
+// level.
+func (cmd *ShellCommand) cd(dir string) error {
+	if dir == "" {
+		dir = "/"
+	}
+	return cmd.db.View(func(tx *bolt.Tx) error {
+		path, err := cmd.resolve(tx, dir)
+		if err != nil {
+			return err
+		}
+		cmd.cwd = path
+		return nil
+	})
+}
+
+func (cmd *ShellCommand) get(key string) error {
+	if key == "" {
+		return ErrKeyRequired
+	}
+	return cmd.db.View(func(tx *bolt.Tx) error {
+		b, err := cmd.current(tx)
+		if err != nil {
+			return err
+		}
+		if b.Bucket([]byte(key)) != nil {
+			return fmt.Errorf("%q %w", key, boltview.ErrNotAKey)
+		}
+		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrKeyNotFound
+		}
+		fmt.Fprintln(cmd.Stdout, string(v))
+		return nil
+	})
+}
+
+func (cmd *ShellCommand) put(key, value string) error {
+	if cmd.db.IsReadOnly() {
+		return errShellReadOnly
+	} else if key == "" {
+		return ErrKeyRequired
+	} else if value == "" {
+		return ErrValueRequired
+	}
+	return cmd.db.Update(func(tx *bolt.Tx) error {
+		b, err := cmd.current(tx)
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(key), []byte(value)); err != nil {
+			return err
+		}
+		return boltview.ClearExpiry(tx, strings.Join(cmd.cwd, "/"), []byte(key))
+	})
+}
+
+func (cmd *ShellCommand) del(key string) error {
+	if cmd.db.IsReadOnly() {
+		return errShellReadOnly
+	} else if key == "" {
+		return ErrKeyRequired
+	}
+	return cmd.db.Update(func(tx *bolt.Tx) error {
+		b, err := cmd.current(tx)
+		if err != nil {
+			return err
+		}
+		if b.Bucket([]byte(key)) != nil {
+			return fmt.Errorf("%q %w", key, boltview.ErrNotAKey)
+		} else if b.Get([]byte(key)) == nil {
+			return ErrKeyNotFound
+		}
+		if err := b.Delete([]byte(key)); err != nil {
+			return err
+		}
+		return boltview.ClearExpiry(tx, strings.Join(cmd.cwd, "/"), []byte(key))
+	})
+}
+
+const shellHelp = `ls [BUCKET]       list the keys in a bucket; nested buckets end in "/"
+cd [BUCKET]       change the current bucket; ".." is the parent and "/"
+                  or no argument the top level
+get KEY           print the value of KEY in the current bucket
+put KEY VALUE     set KEY; the value is the rest of the line
+del KEY           delete KEY
+history           list the lines entered so far
+!!, !N            repeat the last line, or line N of the history
+exit              leave the shell; so does end of input
+`
+
+func (cmd *ShellCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt shell [-rw] PATH
+
+Shell opens the database once and reads commands from a prompt, which is
+quicker than running a bolt command for each step when exploring:
+
+	bolt:/> cd users
+	bolt:/users> ls
+	bolt:/users> get alice
+	bolt:/users> put bob {"name": "Bob"}
+
+The commands are:
+
+`+shellHelp+`
+Each command runs in its own transaction. Errors are printed and the
+shell carries on. Lines are read as they are, without line editing or
+tab completion; run it under a wrapper such as rlwrap for those:
+
+	rlwrap bolt shell my.db
+
+The database is opened read-only, which only takes a shared lock and
+doesn't block the application that owns it; put and del fail.
+
+Additional options include:
+
+	-rw
+		Open the database for writing so put and del work. The
+		database stays locked for writing until the shell exits.
+`, "\n")
+}
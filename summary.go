@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+type SummaryCommand struct {
+	CommonCommand
+}
+
+func newSummaryCommand(m *Main) *SummaryCommand {
+	return &SummaryCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *SummaryCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addSizeFlags(fs)
+	help := fs.Bool("h", false, "")
+	deep := fs.Bool("deep", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	// Open database.
+	path := cmd.path(fs)
+	db, err := cmd.openDB(path, true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	// A database read from stdin has no file left to stat, so fall back to
+	// the size bolt has in use.
+	fileSize := int64(-1)
+	if path != "-" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fileSize = fi.Size()
+	}
+
+	var buckets, keys, valueBytes int64
+	err = db.View(func(tx *bolt.Tx) error {
+		if fileSize < 0 {
+			fileSize = tx.Size()
+		}
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			// Every nested bucket is also a key of its parent, so take
+			// those out of the key count.
+			stats := b.Stats()
+			buckets += int64(stats.BucketN)
+			keys += int64(stats.KeyN - (stats.BucketN - 1))
+			if *deep {
+				valueBytes += sumValues(b)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	stats := db.Stats()
+
+	fmt.Fprintf(cmd.Stdout, "Buckets:     %d\n", buckets)
+	fmt.Fprintf(cmd.Stdout, "Keys:        %d\n", keys)
+	if *deep {
+		fmt.Fprintf(cmd.Stdout, "Value bytes: %s\n", cmd.formatBytes(valueBytes))
+	}
+	fmt.Fprintf(cmd.Stdout, "File size:   %s\n", cmd.formatBytes(fileSize))
+	fmt.Fprintf(cmd.Stdout, "Page size:   %d\n", db.Info().PageSize)
+	fmt.Fprintf(cmd.Stdout, "Free pages:  %d\n", stats.FreePageN+stats.PendingPageN)
+	return nil
+}
+
+// sumValues returns the total size of the values in b and its nested
+// buckets.
+func sumValues(b *bolt.Bucket) int64 {
+	var n int64
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil {
+			n += sumValues(b.Bucket(k))
+		} else {
+			n += int64(len(v))
+		}
+	}
+	return n
+}
+
+func (cmd *SummaryCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt summary [-deep] PATH
+
+Summary prints an overview of the whole database: the number of buckets,
+including nested ones, the number of keys, the file size, the page size
+and the number of free pages. Sizes follow -bytes; see "bolt help".
+
+Additional options include:
+
+	-deep
+		Also scan every bucket and print the total size of all
+		values. This reads the whole database, so it is slow on
+		large files.
+`, "\n")
+}
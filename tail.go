@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type TailCommand struct {
+	CommonCommand
+
+	// interrupt receives SIGINT and stops -follow.
+	interrupt chan os.Signal
+
+	// tick, if set, drives the polls of -follow instead of a ticker
+	// firing every -interval.
+	tick <-chan time.Time
+}
+
+func newTailCommand(m *Main) *TailCommand {
+	return &TailCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+		interrupt: make(chan os.Signal, 1),
+	}
+}
+
+// Run executes the command.
+func (cmd *TailCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	n := fs.Int("n", 10, "")
+	follow := fs.Bool("follow", false, "")
+	interval := fs.Duration("interval", time.Second, "")
+	keyType := fs.String("key-type", typeString, "")
+	valueType := fs.String("value-type", typeString, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := checkType(*keyType); err != nil {
+		return err
+	} else if err := checkType(*valueType); err != nil {
+		return err
+	}
+
+	cmd.maxArgs = 1
+
+	path := cmd.path(fs)
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	printPair := func(k, v []byte) {
+		fmt.Fprintf(cmd.Stdout, "%s\t%s\n", decodeType(*keyType, k), decodeType(*valueType, v))
+	}
+
+	// Print the last entries.
+	db, err := cmd.openDB(path, true)
+	if err != nil {
+		return err
+	}
+	pairs, err := boltview.Tail(db, bucketName, *n)
+	_ = db.Close()
+	if err != nil {
+		return err
+	}
+	var last []byte
+	for _, p := range pairs {
+		printPair(p.Key, p.Value)
+		last = p.Key
+	}
+	if !*follow {
+		return nil
+	}
+
+	signal.Notify(cmd.interrupt, os.Interrupt)
+	defer signal.Stop(cmd.interrupt)
+
+	tick := cmd.tick
+	if tick == nil {
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// Bolt has no change feed, so reopen the database on each tick and
+	// print every key after the last one seen.
+	for {
+		select {
+		case <-cmd.interrupt:
+			return nil
+		case <-tick:
+		}
+
+		db, err := cmd.openDB(path, true)
+		if errors.Is(err, ErrLocked) {
+			// A writer is holding the lock; try again on the next tick.
+			continue
+		} else if err != nil {
+			return err
+		}
+		err = boltview.Scan(db, bucketName, boltview.ScanOptions{After: last}, func(k, v []byte) error {
+			printPair(k, v)
+			last = append(last[:0:0], k...)
+			return nil
+		})
+		_ = db.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (cmd *TailCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt tail [-n N] [-follow] [-interval 1s] [-key-type TYPE]
+                 [-value-type TYPE] PATH BUCKET_NAME
+
+Tail prints the last N key-value pairs in the bucket as "key<TAB>value"
+lines, in key order.
+
+Additional options include:
+
+	-n N
+		Print the last N pairs (default 10).
+	-follow
+		Keep running and print pairs with keys greater than the last
+		one printed as they are added. This suits append-only buckets,
+		such as those written with "insert -seq". Press Ctrl-C to stop.
+	-interval DURATION
+		How often -follow polls the database (default 1s). Bolt has
+		no change feed, so the database is reopened on each poll. A
+		poll waits for the lock as set by -timeout; if that times
+		out, the poll is skipped and the next one tries again.
+	-key-type TYPE, -value-type TYPE
+		Decode keys or values as TYPE for display: string (the
+		default), hex, base64, uint32be or uint64be.
+`, "\n")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type TruncateCommand struct {
+	CommonCommand
+}
+
+func newTruncateCommand(m *Main) *TruncateCommand {
+	return &TruncateCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *TruncateCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	keepSequence := fs.Bool("keep-sequence", false, "")
+	dryRun := fs.Bool("dry-run", false, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), *dryRun)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	if *dryRun {
+		return db.View(func(tx *bolt.Tx) error {
+			bucket, err := boltview.LookupBucket(tx, bucketName)
+			if err != nil {
+				return err
+			}
+			return bucket.ForEach(func(k, v []byte) error {
+				if v == nil {
+					fmt.Fprintf(cmd.Stdout, "- %s/%s\n", bucketName, k)
+				} else {
+					fmt.Fprintf(cmd.Stdout, "- %s\t%s\n", bucketName, k)
+				}
+				return nil
+			})
+		})
+	}
+	return boltview.Truncate(db, bucketName, *keepSequence)
+}
+
+func (cmd *TruncateCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt truncate [-keep-sequence] [-dry-run] PATH BUCKET_NAME
+
+Truncate removes every key-value pair and nested bucket from the bucket
+but keeps the (now empty) bucket. By default the bucket is dropped and
+recreated in one transaction, which also resets its sequence counter.
+
+Additional options include:
+
+	-keep-sequence
+		Delete the keys one by one so the bucket's sequence counter
+		is preserved. Slower on large buckets.
+	-dry-run
+		Print a "- BUCKET<TAB>KEY" line for every key and a
+		"- BUCKET/SUB" line for every nested bucket that would be
+		removed, without modifying the database.
+`, "\n")
+}
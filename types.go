@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Types accepted by the -key-type and -value-type flags.
+const (
+	typeString   = "string"
+	typeHex      = "hex"
+	typeBase64   = "base64"
+	typeUint32BE = "uint32be"
+	typeUint64BE = "uint64be"
+)
+
+// checkType returns an error if typ is not a known key/value type.
+func checkType(typ string) error {
+	switch typ {
+	case typeString, typeHex, typeBase64, typeUint32BE, typeUint64BE:
+		return nil
+	}
+	return fmt.Errorf("unknown type %q: must be string, hex, base64, uint32be or uint64be", typ)
+}
+
+// encodingFlag is a flag naming the encoding of binary arguments: raw, hex
+// or base64. It is an alias that sets each of the -key-type and
+// -value-type values it points to, with raw meaning string.
+type encodingFlag []*string
+
+func (f encodingFlag) String() string { return "" }
+
+func (f encodingFlag) Set(s string) error {
+	typ := s
+	switch s {
+	case formatRaw:
+		typ = typeString
+	case typeHex, typeBase64:
+	default:
+		return errors.New("must be raw, hex or base64")
+	}
+	for _, p := range f {
+		*p = typ
+	}
+	return nil
+}
+
+// Formats accepted by -format-key and -format-value besides the types
+// above: raw shows the bytes as-is and json-pretty indents JSON values.
+const (
+	formatRaw        = "raw"
+	formatJSONPretty = "json-pretty"
+)
+
+// parseFormat maps a -format-key or -format-value name to the type used to
+// display it and whether JSON is indented. Keys can't use json-pretty.
+func parseFormat(format string, value bool) (typ string, pretty bool, err error) {
+	switch format {
+	case formatRaw:
+		return typeString, false, nil
+	case formatJSONPretty:
+		if !value {
+			return "", false, fmt.Errorf("%s is only a value format", format)
+		}
+		return typeString, true, nil
+	}
+	if err := checkType(format); err != nil {
+		return "", false, fmt.Errorf("unknown format %q: must be raw, hex, base64, uint32be, uint64be or json-pretty", format)
+	}
+	return format, false, nil
+}
+
+// encodeKey is like encodeType for keys. Bolt rejects empty keys, so a key
+// that encodes to zero bytes is reported as ErrKeyRequired up front.
+func encodeKey(typ, s string) ([]byte, error) {
+	k, err := encodeType(typ, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s key: %s", typ, err)
+	} else if len(k) == 0 {
+		return nil, fmt.Errorf("%w: %q is an empty %s key", ErrKeyRequired, s, typ)
+	}
+	return k, nil
+}
+
+// encodeType converts a command line argument to the raw bytes stored for
+// the given type, e.g. "42" as uint64be becomes 8 big-endian bytes.
+func encodeType(typ, s string) ([]byte, error) {
+	switch typ {
+	case typeHex:
+		return hex.DecodeString(s)
+	case typeBase64:
+		return base64.StdEncoding.DecodeString(s)
+	case typeUint32BE:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(n))
+		return b, nil
+	case typeUint64BE:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, n)
+		return b, nil
+	}
+	return []byte(s), nil
+}
+
+// decodeType formats raw bytes of the given type for display. Integers of
+// the wrong length can't be decoded and are shown as hex instead.
+func decodeType(typ string, b []byte) string {
+	switch typ {
+	case typeHex:
+		return hex.EncodeToString(b)
+	case typeBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case typeUint32BE:
+		if len(b) == 4 {
+			return strconv.FormatUint(uint64(binary.BigEndian.Uint32(b)), 10)
+		}
+		return hex.EncodeToString(b)
+	case typeUint64BE:
+		if len(b) == 8 {
+			return strconv.FormatUint(binary.BigEndian.Uint64(b), 10)
+		}
+		return hex.EncodeToString(b)
+	}
+	return string(b)
+}
+
+// Output formats accepted by the -format flag of the read commands.
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	// outputCSV is accepted by list only.
+	outputCSV = "csv"
+)
+
+// formatFlag is a boolean flag such as -json that is short for -format
+// with its own name.
+type formatFlag struct {
+	format *string
+	name   string
+}
+
+func (f formatFlag) String() string   { return "" }
+func (f formatFlag) IsBoolFlag() bool { return true }
+
+func (f formatFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	} else if on {
+		*f.format = f.name
+	}
+	return nil
+}
+
+// checkOutput returns an error if format is not a known -format value.
+func checkOutput(format string) error {
+	if format != outputText && format != outputJSON {
+		return fmt.Errorf("unknown format %q: must be text or json", format)
+	}
+	return nil
+}
+
+// jsonPair is a key-value pair as printed by -format json and export. A
+// nested bucket has no value and is marked as nested instead.
+type jsonPair struct {
+	Bucket         string  `json:"bucket,omitempty"`
+	BucketEncoding string  `json:"bucket_encoding,omitempty"`
+	Key            string  `json:"key"`
+	KeyEncoding    string  `json:"key_encoding,omitempty"`
+	Value          *string `json:"value"`
+	ValueEncoding  string  `json:"value_encoding,omitempty"`
+	Nested         bool    `json:"nested,omitempty"`
+	Sequence       uint64  `json:"sequence,omitempty"`
+}
+
+// newJSONPair returns the stored pair k, v in the bucket at path, or the
+// nested bucket k if v is nil, with binary data base64 encoded.
+func newJSONPair(path string, k, v []byte) jsonPair {
+	p := jsonPair{Nested: v == nil}
+	p.Bucket, p.BucketEncoding = jsonText(path)
+	p.Key, p.KeyEncoding = jsonText(string(k))
+	if v != nil {
+		var value string
+		value, p.ValueEncoding = jsonText(string(v))
+		p.Value = &value
+	}
+	return p
+}
+
+// jsonText returns s as-is if it is valid UTF-8, or base64 encoded along
+// with the encoding "base64" so binary data survives the JSON output.
+func jsonText(s string) (text, encoding string) {
+	if utf8.ValidString(s) {
+		return s, ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(s)), typeBase64
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodeType_RoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		typ   string
+		s     string
+		bytes []byte
+	}{
+		{typeUint64BE, "0", []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{typeUint64BE, "42", []byte{0, 0, 0, 0, 0, 0, 0, 42}},
+		{typeUint64BE, "256", []byte{0, 0, 0, 0, 0, 0, 1, 0}},
+		{typeUint64BE, "18446744073709551615", []byte{255, 255, 255, 255, 255, 255, 255, 255}},
+		{typeUint32BE, "42", []byte{0, 0, 0, 42}},
+		{typeHex, "00ff", []byte{0, 255}},
+		{typeBase64, "AP8=", []byte{0, 255}},
+		{typeString, "k", []byte("k")},
+	} {
+		b, err := encodeType(tt.typ, tt.s)
+		if err != nil {
+			t.Errorf("encode %s %q: %s", tt.typ, tt.s, err)
+			continue
+		} else if !bytes.Equal(b, tt.bytes) {
+			t.Errorf("encode %s %q = %v, want %v", tt.typ, tt.s, b, tt.bytes)
+		}
+		if s := decodeType(tt.typ, b); s != tt.s {
+			t.Errorf("decode %s %v = %q, want %q", tt.typ, b, s, tt.s)
+		}
+	}
+}
+
+func TestEncodeType_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		typ string
+		s   string
+	}{
+		{typeUint64BE, "-1"},
+		{typeUint64BE, "18446744073709551616"},
+		{typeUint64BE, "x"},
+		{typeUint32BE, "4294967296"},
+		{typeHex, "zz"},
+	} {
+		if _, err := encodeType(tt.typ, tt.s); err == nil {
+			t.Errorf("encode %s %q: expected an error", tt.typ, tt.s)
+		}
+	}
+}
+
+// Integers of the wrong length are shown as hex.
+func TestDecodeType_WrongLength(t *testing.T) {
+	if s := decodeType(typeUint64BE, []byte{1, 2, 3}); s != "010203" {
+		t.Fatalf("decode uint64be of 3 bytes = %q, want %q", s, "010203")
+	}
+}
+
+// A key that decodes to nothing is refused before bolt sees it.
+func TestEncodeKey_Empty(t *testing.T) {
+	for _, typ := range []string{typeString, typeHex, typeBase64} {
+		_, err := encodeKey(typ, "")
+		if !errors.Is(err, ErrKeyRequired) {
+			t.Errorf("encodeKey %s: error %v, want %v", typ, err, ErrKeyRequired)
+		} else if !strings.Contains(err.Error(), "empty "+typ+" key") {
+			t.Errorf("encodeKey %s: error %q doesn't say the key is empty", typ, err)
+		}
+	}
+	path := tempDB(t, map[string][]string{"b": nil})
+	for _, args := range [][]string{
+		{"insert", "-key-type", "hex", path, "b", "", "v"},
+		{"delete", "-key-type", "hex", path, "b", ""},
+	} {
+		if err := newTestMain().Run(args...); !errors.Is(err, ErrKeyRequired) {
+			t.Errorf("%q: error %v, want %v", args, err, ErrKeyRequired)
+		}
+	}
+}
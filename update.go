@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type UpdateCommand struct {
+	CommonCommand
+}
+
+func newUpdateCommand(m *Main) *UpdateCommand {
+	return &UpdateCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+	}
+}
+
+// Run executes the command.
+func (cmd *UpdateCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	cmd.addWriteFlags(fs)
+	help := fs.Bool("h", false, "")
+	keyType := fs.String("key-type", typeString, "")
+	valueType := fs.String("value-type", typeString, "")
+	fs.Var(encodingFlag{keyType, valueType}, "input-encoding", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	} else if err := exclusive(fs, "input-encoding", "key-type"); err != nil {
+		return err
+	} else if err := exclusive(fs, "input-encoding", "value-type"); err != nil {
+		return err
+	} else if err := checkType(*keyType); err != nil {
+		return err
+	} else if err := checkType(*valueType); err != nil {
+		return err
+	}
+
+	cmd.maxArgs = 3
+
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+	key := cmd.arg(fs, 1)
+	if key == "" {
+		return ErrKeyRequired
+	}
+	value := cmd.arg(fs, 2)
+	if value == "" {
+		return ErrValueRequired
+	}
+	k, err := encodeKey(*keyType, key)
+	if err != nil {
+		return err
+	}
+	v, err := encodeType(*valueType, value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %s", *valueType, err)
+	}
+
+	// Open database.
+	db, err := cmd.openDB(cmd.path(fs), false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	return boltview.Update(db, bucketName, k, v)
+}
+
+func (cmd *UpdateCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt update [-key-type TYPE] [-value-type TYPE] [-input-encoding ENCODING]
+                   PATH BUCKET_NAME KEY VALUE
+
+Update replaces the value of an existing key in the bucket. Unlike insert
+it fails with "key not found" instead of creating a new key.
+
+Additional options include:
+
+	-key-type TYPE, -value-type TYPE
+		Encode KEY or VALUE as TYPE, as in insert.
+	-input-encoding ENCODING
+		Decode both KEY and VALUE as raw, hex or base64.
+`, "\n")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/coldTea214/bolttools/boltview"
+)
+
+type WatchCommand struct {
+	CommonCommand
+
+	// interrupt receives SIGINT and stops the watch.
+	interrupt chan os.Signal
+
+	// tick, if set, drives the polls instead of a ticker firing every
+	// -interval.
+	tick <-chan time.Time
+}
+
+func newWatchCommand(m *Main) *WatchCommand {
+	return &WatchCommand{
+		CommonCommand: CommonCommand{
+			Stdin:  m.Stdin,
+			Stdout: m.Stdout,
+			Stderr: m.Stderr,
+		},
+		interrupt: make(chan os.Signal, 1),
+	}
+}
+
+// Run executes the command.
+func (cmd *WatchCommand) Run(args ...string) error {
+	// Parse flags.
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	cmd.addFlags(fs)
+	help := fs.Bool("h", false, "")
+	interval := fs.Duration("interval", time.Second, "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	} else if *help {
+		fmt.Fprintln(cmd.Stderr, cmd.Usage())
+		return ErrUsage
+	}
+
+	cmd.maxArgs = 1
+
+	path := cmd.path(fs)
+	bucketName := cmd.arg(fs, 0)
+	if bucketName == "" {
+		return ErrBucketRequired
+	}
+
+	// Take the initial snapshot that the first tick is compared against.
+	prev, err := cmd.snapshot(path, bucketName)
+	if err != nil {
+		return err
+	}
+
+	signal.Notify(cmd.interrupt, os.Interrupt)
+	defer signal.Stop(cmd.interrupt)
+
+	tick := cmd.tick
+	if tick == nil {
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-cmd.interrupt:
+			return nil
+		case <-tick:
+		}
+
+		curr, err := cmd.snapshot(path, bucketName)
+		if errors.Is(err, ErrLocked) {
+			// A writer is holding the lock; try again on the next tick.
+			continue
+		} else if err != nil {
+			return err
+		}
+		cmd.printChanges(prev, curr)
+		prev = curr
+	}
+}
+
+// snapshot reopens the database read-only and copies every key-value pair
+// in the bucket so the file is not held open between ticks. Nested buckets
+// are stored with a nil value, and every other value is non-nil.
+func (cmd *WatchCommand) snapshot(path, bucketName string) (map[string][]byte, error) {
+	db, err := cmd.openDB(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	m := make(map[string][]byte)
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket, err := boltview.LookupBucket(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v != nil {
+				v = append([]byte{}, v...)
+			}
+			m[string(k)] = v
+			return nil
+		})
+	})
+	return m, err
+}
+
+// printChanges writes one line per added (+), removed (-) or changed (~)
+// key between two snapshots, in key order.
+func (cmd *WatchCommand) printChanges(prev, curr map[string][]byte) {
+	keys := make([]string, 0, len(curr))
+	for k := range curr {
+		keys = append(keys, k)
+	}
+	for k := range prev {
+		if _, ok := curr[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		old, hadOld := prev[k]
+		v, hasNew := curr[k]
+		switch {
+		case !hadOld:
+			fmt.Fprintf(cmd.Stdout, "+ %s\t%s\n", k, watchValue(v))
+		case !hasNew:
+			fmt.Fprintf(cmd.Stdout, "- %s\n", k)
+		case (old == nil) != (v == nil) || !bytes.Equal(old, v):
+			fmt.Fprintf(cmd.Stdout, "~ %s\t%s\n", k, watchValue(v))
+		}
+	}
+}
+
+// watchValue formats a snapshot value for display. Nested buckets have no
+// value of their own, so they are marked as in list.
+func watchValue(v []byte) string {
+	if v == nil {
+		return "[bucket]"
+	}
+	return string(v)
+}
+
+func (cmd *WatchCommand) Usage() string {
+	return strings.TrimLeft(`
+usage: bolt watch PATH BUCKET_NAME [-interval 1s]
+
+Watch polls the bucket every interval and prints the keys that were added
+(+), removed (-) or changed (~) since the previous poll. Bolt has no change
+feed, so the database is reopened read-only on each tick. Nested buckets
+are shown with a "[bucket]" value. Press Ctrl-C to stop.
+
+Additional options include:
+
+	-interval DURATION
+		How often to poll the database (default 1s). A poll waits
+		for the lock as set by -timeout; if that times out, the poll
+		is skipped and the next one tries again.
+`, "\n")
+}